@@ -1,12 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/yejune/gorelay/internal/config"
 	"github.com/yejune/gorelay/internal/runner"
+	"golang.org/x/term"
 )
 
 func Execute(args []string) error {
@@ -26,7 +31,10 @@ func Execute(args []string) error {
 		if len(args) < 2 {
 			return fmt.Errorf("usage: gorelay run <task> [--on=server] [-v]")
 		}
-		return runTask(args[1], parseServer(args), parseVerbose(args))
+		return runTask(args[1], args[2:])
+
+	case "rollback":
+		return rollback(args[1:])
 
 	case "init":
 		return initConfig()
@@ -47,11 +55,16 @@ func Execute(args []string) error {
 
 	default:
 		// 기본: task 이름으로 간주
-		return runTask(command, parseServer(args), parseVerbose(args))
+		return runTask(command, args[1:])
 	}
 }
 
-func runTask(taskName string, serverFilter string, verbose bool) error {
+func runTask(taskName string, args []string) error {
+	forks, err := parseForks(args)
+	if err != nil {
+		return err
+	}
+
 	cfg, err := config.Load("Gorelayfile.yaml")
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
@@ -60,11 +73,84 @@ func runTask(taskName string, serverFilter string, verbose bool) error {
 	r := runner.New(cfg)
 	defer r.Close()
 
-	if verbose {
+	if parseVerbose(args) {
 		r.SetVerbose(true)
 	}
+	r.SetForks(forks)
+	if err := r.SetOutput(parseOutput(args)); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handleInterrupt(cancel)
+
+	return r.Run(ctx, taskName, parseServer(args))
+}
+
+// rollback switches release steps back to an earlier release. A task named
+// "rollback" in Gorelayfile.yaml still takes precedence, so the built-in
+// command's flags are refused rather than silently ignored.
+func rollback(args []string) error {
+	cfg, err := config.Load("Gorelayfile.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if _, ok := cfg.Tasks["rollback"]; ok {
+		for _, arg := range args {
+			if arg == "--list" || arg == "--force" || strings.HasPrefix(arg, "--to=") || strings.HasPrefix(arg, "--task=") {
+				return fmt.Errorf("%s is an option of the built-in rollback command, but Gorelayfile.yaml defines a task named rollback; rename the task to use it", strings.SplitN(arg, "=", 2)[0])
+			}
+		}
+		return runTask("rollback", args)
+	}
+
+	opts := runner.RollbackOptions{Server: parseServer(args)}
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--to="):
+			opts.To = strings.TrimPrefix(arg, "--to=")
+		case strings.HasPrefix(arg, "--task="):
+			opts.Task = strings.TrimPrefix(arg, "--task=")
+		case arg == "--list":
+			opts.List = true
+		case arg == "--force":
+			opts.Force = true
+		}
+	}
+
+	r := runner.New(cfg)
+	defer r.Close()
+	r.SetVerbose(parseVerbose(args))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handleInterrupt(cancel)
+
+	return r.Rollback(ctx, opts)
+}
 
-	return r.Run(taskName, serverFilter)
+// handleInterrupt cancels the run on the first Ctrl-C (remote commands are
+// signalled and cleaned up) and force-exits on the second, restoring the
+// terminal a tty step may have left in raw mode.
+func handleInterrupt(cancel context.CancelFunc) {
+	// tty 단계가 raw 모드로 바꾸기 전의 터미널 상태
+	fd := int(os.Stdin.Fd())
+	state, _ := term.GetState(fd)
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	<-signals
+	fmt.Fprintln(os.Stderr, "\n⚠ Interrupted: stopping remote commands and cleaning up (press Ctrl-C again to force quit)")
+	cancel()
+
+	<-signals
+	if state != nil {
+		term.Restore(fd, state)
+	}
+	fmt.Fprintln(os.Stderr, "⚠ Forced exit")
+	os.Exit(130)
 }
 
 func listTasks() error {
@@ -107,21 +193,19 @@ tasks:
     description: "Deploy to production"
     on: [production]
     scripts:
-      - local: GOOS=linux GOARCH=amd64 go build -ldflags="-s -w" -o server-linux .
-      - upload: server-linux:/app/server-new
-      - run: |
-          cd /app
-          mv server server-old 2>/dev/null || true
-          mv server-new server
-          chmod +x server
-          sudo systemctl restart myapp
-      - local: rm -f server-linux
+      - local: GOOS=linux GOARCH=amd64 go build -ldflags="-s -w" -o server .
+      - release: server:/app        # /app/releases/<timestamp>/server, /app/current → 최신 릴리스
+        shared: [storage]
+        keep: 5
+      - run: sudo systemctl restart myapp   # ExecStart=/app/current/server
+      - local: rm -f server
 
   logs:
     description: "View logs"
     on: [production]
     scripts:
       - run: sudo journalctl -u myapp -f
+        tty: true
 
   status:
     description: "Check service status"
@@ -129,15 +213,12 @@ tasks:
     scripts:
       - run: sudo systemctl status myapp --no-pager
 
-  rollback:
-    description: "Rollback to previous version"
+  # 이전 릴리스로 되돌리기: gorelay rollback (또는 --to=<릴리스>)
+  restart:
+    description: "Restart service"
     on: [production]
     scripts:
-      - run: |
-          cd /app
-          mv server server-failed
-          mv server-old server
-          sudo systemctl restart myapp
+      - run: sudo systemctl restart myapp
 `
 
 	if err := os.WriteFile("Gorelayfile.yaml", []byte(example), 0644); err != nil {
@@ -157,6 +238,30 @@ func parseServer(args []string) string {
 	return ""
 }
 
+// parseForks reads --forks=N (0 when not given)
+func parseForks(args []string) (int, error) {
+	for _, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--forks="); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid --forks: %s (expected a positive number)", value)
+			}
+			return n, nil
+		}
+	}
+	return 0, nil
+}
+
+// parseOutput reads --output=buffered|stream|summary
+func parseOutput(args []string) string {
+	for _, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--output="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
 func parseVerbose(args []string) bool {
 	for _, arg := range args {
 		if arg == "-v" || arg == "--verbose" || strings.HasPrefix(arg, "-v") {
@@ -174,7 +279,12 @@ Usage:
   gorelay <task> -v           Run with verbose output
   gorelay run <task>          Run a task (explicit)
   gorelay run <task> --on=X   Run on specific server
+  gorelay <task> --forks=N    Run on at most N servers at once
+  gorelay <task> --output=X   Parallel output: buffered, stream or summary
   gorelay list                List available tasks
+  gorelay rollback            Switch release steps back to the previous release
+  gorelay rollback --list     List releases on each server
+  gorelay rollback --to=X     Switch to release X (--force if not in releases.log)
   gorelay init                Create example Gorelayfile.yaml
   gorelay version             Show version
   gorelay self-update         Update to latest version
@@ -182,6 +292,9 @@ Usage:
 Options:
   -v, --verbose             Show detailed output (timing, checksums, etc.)
   --on=<server>             Run on specific server only
+  --forks=<n>               Servers running at once (overrides task concurrency)
+  --output=<mode>           Parallel output: buffered (default), stream, summary
+  --task=<name>             rollback: task whose release step to use
 
 Examples:
   gorelay deploy              Deploy to production
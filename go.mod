@@ -4,6 +4,7 @@ go 1.25.4
 
 require (
 	golang.org/x/crypto v0.45.0
+	golang.org/x/term v0.37.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
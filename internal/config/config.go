@@ -2,16 +2,22 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type GorelayConfig struct {
-	Servers map[string]Server `yaml:"servers"`
-	Tasks   map[string]Task   `yaml:"tasks"`
-	Log     LogConfig         `yaml:"log"`
+	Servers   map[string]Server `yaml:"servers"`
+	Tasks     map[string]Task   `yaml:"tasks"`
+	Log       LogConfig         `yaml:"log"`
+	SSHConfig string            `yaml:"ssh_config"` // OpenSSH config path (default: ~/.ssh/config, "none" to disable)
+
+	sshConfig *sshConfig // Parsed OpenSSH config (for jump hosts)
 }
 
 type LogConfig struct {
@@ -21,27 +27,61 @@ type LogConfig struct {
 
 // Server can have single host or multiple hosts
 type Server struct {
-	Host      string   `yaml:"host"`  // Single host
-	HostsYAML []string `yaml:"hosts"` // Multiple hosts (YAML key)
-	User      string   `yaml:"user"`
-	Port      int      `yaml:"port"`
-	Key       string   `yaml:"key"` // SSH key path
-	Hosts     []string `yaml:"-"`   // Expanded hosts (internal use)
+	Alias          string        `yaml:"alias"` // Host alias in ~/.ssh/config
+	Host           string        `yaml:"host"`  // Single host
+	HostsYAML      []string      `yaml:"hosts"` // Multiple hosts (YAML key)
+	User           string        `yaml:"user"`
+	Port           int           `yaml:"port"`
+	Key            string        `yaml:"key"`             // SSH key path
+	Keys           []string      `yaml:"keys"`            // Additional SSH key paths, tried in order
+	Cert           string        `yaml:"cert"`            // User certificate (default: <key>-cert.pub if present)
+	Auth           string        `yaml:"auth"`            // publickey (default), password, keyboard-interactive
+	PasswordEnv    string        `yaml:"password_env"`    // Env var holding the password (default: prompt)
+	PasswordCmd    string        `yaml:"password_cmd"`    // Command printing the password (default: prompt)
+	KnownHosts     string        `yaml:"known_hosts"`     // Extra known_hosts file (checked with ~/.ssh/known_hosts)
+	HostKey        string        `yaml:"host_key"`        // Pinned host key fingerprint (SHA256:...)
+	HostKeyCheck   string        `yaml:"host_key_check"`  // strict (default), accept-new, off
+	IdentitiesOnly bool          `yaml:"identities_only"` // Only offer configured keys (no other ssh-agent identities)
+	Keepalive      time.Duration `yaml:"keepalive"`       // Keepalive interval, e.g. 30s (default: disabled)
+	KeepaliveMax   int           `yaml:"keepalive_max"`   // Unanswered keepalives before disconnecting (default: 3)
+	Reconnect      int           `yaml:"reconnect"`       // Reconnect attempts when a cached connection died (default: 3)
+	Transfer       string        `yaml:"transfer"`        // File transfer backend: auto (default, SFTP with scp fallback), sftp, scp
+	Jump           string        `yaml:"jump"`            // Jump hosts: server names or user@host:port, comma-separated for multiple hops
+	Hosts          []string      `yaml:"-"`               // Expanded hosts (internal use)
 }
 
 type Task struct {
-	Description string   `yaml:"description"`
-	On          []string `yaml:"on"`       // Server names
-	Parallel    bool     `yaml:"parallel"` // Run on servers in parallel
-	Scripts     []Script `yaml:"scripts"`  // List of scripts
+	Description     string        `yaml:"description"`
+	On              []string      `yaml:"on"`                // Server names
+	Parallel        bool          `yaml:"parallel"`          // Run on servers in parallel
+	Concurrency     int           `yaml:"concurrency"`       // parallel/rolling: servers running at once (default: no limit)
+	Strategy        string        `yaml:"strategy"`          // rolling: run in batches of parallel servers
+	Batch           string        `yaml:"batch"`             // rolling: servers per batch, a count (5) or a percentage (20%) (default: 1)
+	MaxFail         int           `yaml:"max_fail"`          // rolling: failed servers tolerated before remaining batches are skipped (default: 0)
+	Pause           time.Duration `yaml:"pause"`             // rolling: wait between batches, e.g. 30s
+	ContinueOnError bool          `yaml:"continue_on_error"` // Keep running on the other servers after one fails
+	Scripts         []Script      `yaml:"scripts"`           // List of scripts
 }
 
 type Script struct {
-	Local string `yaml:"local"` // Local command
-	Run   string `yaml:"run"`   // Remote command
-	Sync  string `yaml:"sync"`  // Sync upload (changed files only, checksum comparison)
-	Tar   string `yaml:"tar"`   // Tar upload (compress, upload, extract - atomic)
-	Scp   string `yaml:"scp"`   // SCP upload (direct transfer, no checksum)
+	Local        string   `yaml:"local"`         // Local command
+	Run          string   `yaml:"run"`           // Remote command
+	Sync         string   `yaml:"sync"`          // Sync upload (changed files only, checksum comparison)
+	Tar          string   `yaml:"tar"`           // Tar upload (compress, upload, extract - atomic)
+	Scp          string   `yaml:"scp"`           // SCP upload (direct transfer, no checksum)
+	Fetch        string   `yaml:"fetch"`         // Download remote:local into local/<server>/ (checksum verified)
+	Release      string   `yaml:"release"`       // Upload local:base into base/releases/<name> and switch base/current
+	ReleaseName  string   `yaml:"release_name"`  // release: timestamp (default) or git (short commit SHA)
+	Shared       []string `yaml:"shared"`        // release: paths linked from base/shared into every release
+	Keep         int      `yaml:"keep"`          // release: number of releases to keep (default: 5)
+	TTY          bool     `yaml:"tty"`           // Run remote command in an interactive PTY
+	IgnoreErrors bool     `yaml:"ignore_errors"` // Report a failure of this step and go on with the next
+	Preserve     string   `yaml:"preserve"`      // sync/scp metadata to keep: mode, mtime, links, all, none (default: mode,links)
+	Exclude      []string `yaml:"exclude"`       // Upload patterns to skip (gitignore syntax, added to .gorelayignore)
+	Include      []string `yaml:"include"`       // Only upload files matching these patterns
+	Delete       string   `yaml:"delete"`        // sync: remove remote files missing locally (true, false, dry-run)
+	MaxDelete    int      `yaml:"max_delete"`    // sync: refuse to delete more files than this (default: 100)
+	Delta        bool     `yaml:"delta"`         // sync: send only changed blocks of large files (1MB+)
 }
 
 func Load(path string) (*GorelayConfig, error) {
@@ -51,10 +91,7 @@ func Load(path string) (*GorelayConfig, error) {
 	}
 
 	// Expand ~ in path
-	if len(path) > 2 && path[:2] == "~/" {
-		home, _ := os.UserHomeDir()
-		path = filepath.Join(home, path[2:])
-	}
+	path = expandHome(path)
 
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -69,39 +106,46 @@ func Load(path string) (*GorelayConfig, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	// ~/.ssh/config (Host alias, User, Port, IdentityFile 등)
+	sshConfigPath := cfg.SSHConfig
+	if sshConfigPath == "" {
+		sshConfigPath = "~/.ssh/config"
+	}
+	sshCfg := &sshConfig{}
+	if sshConfigPath != "none" {
+		sshCfg, err = loadSSHConfig(sshConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ssh config: %w", err)
+		}
+	}
+
 	// Expand servers with multiple hosts
 	expandedServers := make(map[string]Server)
 	for name, server := range cfg.Servers {
-		// Set defaults
-		if server.Port == 0 {
-			server.Port = 22
-		}
-		if server.User == "" {
-			server.User = os.Getenv("USER")
-		}
-
 		// Determine hosts (prefer HostsYAML over Host)
 		var hosts []string
 		if len(server.HostsYAML) > 0 {
 			hosts = server.HostsYAML
 		} else if server.Host != "" {
 			hosts = []string{server.Host}
+		} else if server.Alias != "" {
+			hosts = []string{""}
 		}
 
 		if len(hosts) == 1 {
 			// Single host - keep as is
-			server.Hosts = hosts
+			server.Host = hosts[0]
+			server = applySSHConfig(server, sshCfg)
+			server.Hosts = []string{server.Host}
 			expandedServers[name] = server
 		} else if len(hosts) > 1 {
 			// Multiple hosts - expand to separate servers
 			for i, host := range hosts {
-				expandedServer := Server{
-					Host:  host,
-					Hosts: []string{host},
-					User:  server.User,
-					Port:  server.Port,
-					Key:   server.Key,
-				}
+				expandedServer := server
+				expandedServer.Host = host
+				expandedServer.HostsYAML = nil
+				expandedServer = applySSHConfig(expandedServer, sshCfg)
+				expandedServer.Hosts = []string{expandedServer.Host}
 				// Name format: web[0], web[1], etc.
 				expandedName := fmt.Sprintf("%s[%d]", name, i)
 				expandedServers[expandedName] = expandedServer
@@ -112,10 +156,63 @@ func Load(path string) (*GorelayConfig, error) {
 		}
 	}
 	cfg.Servers = expandedServers
+	cfg.sshConfig = sshCfg
 
 	return &cfg, nil
 }
 
+// applySSHConfig fills fields missing from the Gorelayfile with ~/.ssh/config values.
+// The alias (or the host itself) is looked up like plain ssh would.
+func applySSHConfig(server Server, sshCfg *sshConfig) Server {
+	alias := server.Alias
+	if alias == "" {
+		alias = server.Host
+	}
+	if alias != "" {
+		host := sshCfg.lookup(alias, server.User)
+
+		if server.Host == "" || server.Host == alias {
+			server.Host = alias
+			if host.HostName != "" {
+				server.Host = host.HostName
+			}
+		}
+		if server.User == "" {
+			server.User = host.User
+		}
+		if server.Port == 0 {
+			server.Port = host.Port
+		}
+		if server.Key == "" && len(server.Keys) == 0 {
+			server.Keys = host.IdentityFiles
+		}
+		if server.Cert == "" {
+			server.Cert = host.CertificateFile
+		}
+		if !server.IdentitiesOnly {
+			server.IdentitiesOnly = host.IdentitiesOnly
+		}
+		if server.Jump == "" {
+			server.Jump = host.ProxyJump
+		}
+		if server.Keepalive == 0 {
+			server.Keepalive = time.Duration(host.AliveInterval) * time.Second
+		}
+		if server.KeepaliveMax == 0 {
+			server.KeepaliveMax = host.AliveCountMax
+		}
+	}
+
+	// Set defaults
+	if server.Port == 0 {
+		server.Port = 22
+	}
+	if server.User == "" {
+		server.User = os.Getenv("USER")
+	}
+	return server
+}
+
 // GetExpandedServers returns server names to run on
 // If server has multiple hosts, returns expanded names (web[0], web[1], etc.)
 func (cfg *GorelayConfig) GetExpandedServers(names []string) []string {
@@ -136,3 +233,53 @@ func (cfg *GorelayConfig) GetExpandedServers(names []string) []string {
 	}
 	return result
 }
+
+// JumpServer resolves one hop of a jump chain: either a server entry, or
+// [user@]host[:port] which inherits key and host key settings from the origin server.
+func (cfg *GorelayConfig) JumpServer(hop string, origin Server) (Server, error) {
+	spec := strings.TrimSpace(hop)
+	if server, ok := cfg.Servers[spec]; ok {
+		if len(server.Hosts) > 1 {
+			return Server{}, fmt.Errorf("jump host '%s' has multiple hosts", spec)
+		}
+		return server, nil
+	}
+
+	server := Server{
+		KnownHosts:   origin.KnownHosts,
+		HostKeyCheck: origin.HostKeyCheck,
+	}
+
+	host := spec
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		server.User = host[:i]
+		host = host[i+1:]
+	}
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return Server{}, fmt.Errorf("invalid jump host port: %s", spec)
+		}
+		host = h
+		server.Port = p
+	}
+	if host == "" {
+		return Server{}, fmt.Errorf("invalid jump host: %s", spec)
+	}
+
+	server.Host = host
+	sshCfg := cfg.sshConfig
+	if sshCfg == nil {
+		sshCfg = &sshConfig{}
+	}
+	server = applySSHConfig(server, sshCfg)
+	server.Hosts = []string{server.Host}
+
+	// ssh config에 키가 없으면 원래 서버의 키 사용
+	if server.Key == "" && len(server.Keys) == 0 {
+		server.Key = origin.Key
+		server.Keys = origin.Keys
+		server.IdentitiesOnly = origin.IdentitiesOnly
+	}
+	return server, nil
+}
@@ -0,0 +1,302 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sshHost holds the OpenSSH options gorelay understands for one host
+type sshHost struct {
+	HostName        string
+	User            string
+	Port            int
+	IdentityFiles   []string
+	CertificateFile string
+	ProxyJump       string
+	IdentitiesOnly  bool
+	AliveInterval   int // ServerAliveInterval (seconds)
+	AliveCountMax   int // ServerAliveCountMax
+}
+
+// sshConfigBlock is a Host section with its options, in file order
+type sshConfigBlock struct {
+	patterns []string
+	options  [][2]string // keyword (lowercase), value
+}
+
+type sshConfig struct {
+	blocks []sshConfigBlock
+}
+
+// loadSSHConfig parses an OpenSSH client config. A missing file is not an error.
+func loadSSHConfig(path string) (*sshConfig, error) {
+	cfg := &sshConfig{}
+	// Host 지정 전 옵션은 모든 호스트에 적용
+	cfg.blocks = append(cfg.blocks, sshConfigBlock{patterns: []string{"*"}})
+	if err := cfg.parseFile(expandHome(path), 0); err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (cfg *sshConfig) parseFile(path string, depth int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		keyword, args := splitSSHConfigLine(scanner.Text())
+		if keyword == "" {
+			continue
+		}
+
+		switch keyword {
+		case "host":
+			cfg.blocks = append(cfg.blocks, sshConfigBlock{patterns: args})
+		case "match":
+			// Match 조건은 지원하지 않으므로 해당 블록은 어떤 호스트와도 일치하지 않음
+			cfg.blocks = append(cfg.blocks, sshConfigBlock{})
+		case "include":
+			if depth >= 16 {
+				continue
+			}
+			for _, pattern := range args {
+				pattern = expandHome(pattern)
+				if !filepath.IsAbs(pattern) {
+					pattern = expandHome(filepath.Join("~/.ssh", pattern))
+				}
+				matches, _ := filepath.Glob(pattern)
+				for _, match := range matches {
+					// Include는 현재 Host 블록 안에서 읽힌 것처럼 이어서 적용
+					current := cfg.blocks[len(cfg.blocks)-1]
+					if err := cfg.parseFile(match, depth+1); err != nil && !os.IsNotExist(err) {
+						return err
+					}
+					cfg.blocks = append(cfg.blocks, sshConfigBlock{patterns: current.patterns})
+				}
+			}
+		default:
+			if len(args) == 0 {
+				continue
+			}
+			if err := checkSSHConfigNumber(keyword, args[0]); err != nil {
+				return fmt.Errorf("%s line %d: %w", path, line, err)
+			}
+			last := &cfg.blocks[len(cfg.blocks)-1]
+			last.options = append(last.options, [2]string{keyword, strings.Join(args, " ")})
+		}
+	}
+	return scanner.Err()
+}
+
+// checkSSHConfigNumber rejects numeric options OpenSSH would refuse to parse
+func checkSSHConfigNumber(keyword, value string) error {
+	switch keyword {
+	case "port":
+		if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid Port: %s", value)
+		}
+	case "serveraliveinterval", "serveralivecountmax":
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return fmt.Errorf("invalid %s: %s", keyword, value)
+		}
+	}
+	return nil
+}
+
+// lookup resolves options for alias. Like OpenSSH, the first value obtained wins,
+// except IdentityFile which accumulates. user is the user set in the Gorelayfile,
+// which takes precedence over User for %r.
+func (cfg *sshConfig) lookup(alias, user string) sshHost {
+	var host sshHost
+	seen := make(map[string]bool)
+
+	for _, block := range cfg.blocks {
+		if !matchHostPatterns(block.patterns, alias) {
+			continue
+		}
+		for _, opt := range block.options {
+			keyword, value := opt[0], opt[1]
+			if keyword == "identityfile" {
+				host.IdentityFiles = append(host.IdentityFiles, value)
+				continue
+			}
+			if seen[keyword] {
+				continue
+			}
+			seen[keyword] = true
+
+			switch keyword {
+			case "hostname":
+				host.HostName = value
+			case "user":
+				host.User = value
+			case "port":
+				host.Port, _ = strconv.Atoi(value)
+			case "certificatefile":
+				host.CertificateFile = value
+			case "proxyjump":
+				if !strings.EqualFold(value, "none") {
+					host.ProxyJump = value
+				}
+			case "identitiesonly":
+				host.IdentitiesOnly = strings.EqualFold(value, "yes")
+			case "serveraliveinterval":
+				host.AliveInterval, _ = strconv.Atoi(value)
+			case "serveralivecountmax":
+				host.AliveCountMax, _ = strconv.Atoi(value)
+			}
+		}
+	}
+
+	if host.HostName != "" {
+		host.HostName = strings.ReplaceAll(host.HostName, "%h", alias)
+	}
+	if user == "" {
+		user = host.User
+	}
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	for i, identity := range host.IdentityFiles {
+		host.IdentityFiles[i] = expandSSHTokens(identity, alias, user, host)
+	}
+	if host.CertificateFile != "" {
+		host.CertificateFile = expandSSHTokens(host.CertificateFile, alias, user, host)
+	}
+	return host
+}
+
+// splitSSHConfigLine splits "Keyword value" or "Keyword=value" lines
+func splitSSHConfigLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil
+	}
+
+	keyword := line
+	rest := ""
+	if i := strings.IndexAny(line, " \t="); i >= 0 {
+		keyword = line[:i]
+		rest = strings.TrimLeft(line[i:], " \t")
+		rest = strings.TrimPrefix(rest, "=")
+	}
+
+	return strings.ToLower(keyword), splitQuoted(rest)
+}
+
+// splitQuoted splits on whitespace, keeping double-quoted strings together
+func splitQuoted(s string) []string {
+	var fields []string
+	var current strings.Builder
+	inQuote := false
+	hasField := false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			hasField = true
+		case (r == ' ' || r == '\t') && !inQuote:
+			if hasField {
+				fields = append(fields, current.String())
+				current.Reset()
+				hasField = false
+			}
+		default:
+			current.WriteRune(r)
+			hasField = true
+		}
+	}
+	if hasField {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+// matchHostPatterns applies Host patterns, where a matching !pattern excludes the host
+func matchHostPatterns(patterns []string, host string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		for _, p := range strings.Split(pattern, ",") {
+			negate := strings.HasPrefix(p, "!")
+			p = strings.TrimPrefix(p, "!")
+			if !matchWildcard(p, host) {
+				continue
+			}
+			if negate {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchWildcard matches s against an OpenSSH pattern, where only * and ? are special
+func matchWildcard(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// 연속된 *는 하나로 취급하고 남은 패턴이 맞는 위치를 찾음
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchWildcard(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+			s = s[1:]
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+			s = s[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return s == ""
+}
+
+// expandSSHTokens expands the IdentityFile tokens %d, %h, %r, %u and %%.
+// user is the effective remote user.
+func expandSSHTokens(s, alias, user string, host sshHost) string {
+	home, _ := os.UserHomeDir()
+	hostname := host.HostName
+	if hostname == "" {
+		hostname = alias
+	}
+	replacer := strings.NewReplacer(
+		"%%", "%",
+		"%d", home,
+		"%h", hostname,
+		"%r", user,
+		"%u", os.Getenv("USER"),
+	)
+	return replacer.Replace(s)
+}
+
+func expandHome(path string) string {
+	if len(path) > 2 && path[:2] == "~/" {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeSSHConfig writes files (relative to dir) and loads dir/config
+func writeSSHConfig(t *testing.T, dir string, files map[string]string) *sshConfig {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := loadSSHConfig(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestSSHConfigLookup(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("USER", "local")
+
+	cfg := writeSSHConfig(t, dir, map[string]string{
+		"config": `
+# Host 전 옵션은 모든 호스트에 적용
+IdentityFile ~/.ssh/global
+
+Host web
+    HostName web.internal
+    User deploy
+    Port 2222
+    IdentityFile ~/.ssh/%r@%h
+
+Host web*
+    User ignored
+    Port 22
+    ProxyJump bastion
+    ServerAliveInterval 30
+
+Match host web
+    User from-match
+
+Host db !db-old
+    HostName %h.internal
+    IdentitiesOnly yes
+    CertificateFile ~/.ssh/%r-cert.pub
+
+Host *
+    User=fallback
+    ServerAliveCountMax 5
+    ProxyJump none
+`,
+	})
+
+	tests := []struct {
+		alias, user string
+		want        sshHost
+	}{
+		{"web", "", sshHost{
+			HostName:      "web.internal",
+			User:          "deploy",
+			Port:          2222,
+			IdentityFiles: []string{"~/.ssh/global", "~/.ssh/deploy@web.internal"},
+			ProxyJump:     "bastion",
+			AliveInterval: 30,
+			AliveCountMax: 5,
+		}},
+		// Gorelayfile의 user가 %r에 쓰임
+		{"web", "admin", sshHost{
+			HostName:      "web.internal",
+			User:          "deploy",
+			Port:          2222,
+			IdentityFiles: []string{"~/.ssh/global", "~/.ssh/admin@web.internal"},
+			ProxyJump:     "bastion",
+			AliveInterval: 30,
+			AliveCountMax: 5,
+		}},
+		{"web2", "", sshHost{
+			User:          "ignored",
+			Port:          22,
+			IdentityFiles: []string{"~/.ssh/global"},
+			ProxyJump:     "bastion",
+			AliveInterval: 30,
+			AliveCountMax: 5,
+		}},
+		{"db", "", sshHost{
+			HostName:        "db.internal",
+			User:            "fallback",
+			IdentityFiles:   []string{"~/.ssh/global"},
+			CertificateFile: "~/.ssh/fallback-cert.pub",
+			IdentitiesOnly:  true,
+			AliveCountMax:   5,
+		}},
+		// !db-old가 Host db 블록을 제외
+		{"db-old", "", sshHost{
+			User:          "fallback",
+			IdentityFiles: []string{"~/.ssh/global"},
+			AliveCountMax: 5,
+		}},
+	}
+	for _, tt := range tests {
+		if got := cfg.lookup(tt.alias, tt.user); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lookup(%q, %q) =\n  %+v\nwant\n  %+v", tt.alias, tt.user, got, tt.want)
+		}
+	}
+}
+
+func TestSSHConfigInclude(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	cfg := writeSSHConfig(t, dir, map[string]string{
+		"config": `
+Host app
+    Include conf.d/*.conf
+    Port 2200
+
+Host other
+    User other
+`,
+		// 상대 경로는 ~/.ssh 기준
+		".ssh/conf.d/a.conf": `
+User included
+Host nested
+    User nested
+`,
+		".ssh/conf.d/b.conf": `
+Port 2201
+`,
+	})
+
+	// include 안의 값이 Include 줄 뒤의 값보다 먼저 읽힘
+	app := cfg.lookup("app", "")
+	if app.User != "included" || app.Port != 2201 {
+		t.Errorf("app = %+v, want User included and Port 2201 from the includes", app)
+	}
+	if nested := cfg.lookup("nested", ""); nested.User != "nested" || nested.Port != 0 {
+		t.Errorf("nested = %+v, want User nested only", nested)
+	}
+	// a.conf의 Host nested가 끝나면 b.conf는 다시 Host app 블록에 속함
+	if other := cfg.lookup("other", ""); other.User != "other" || other.Port != 0 {
+		t.Errorf("other = %+v, want User other only", other)
+	}
+}
+
+func TestSSHConfigMissingFile(t *testing.T) {
+	cfg, err := loadSSHConfig(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.lookup("web", "u"); got.HostName != "" || got.Port != 0 {
+		t.Errorf("lookup = %+v, want empty", got)
+	}
+}
+
+func TestSSHConfigInvalidNumbers(t *testing.T) {
+	for _, line := range []string{"Port abc", "Port 0", "Port 70000", "ServerAliveInterval x", "ServerAliveCountMax -1"} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config")
+		os.WriteFile(path, []byte("Host web\n    "+line+"\n"), 0644)
+		_, err := loadSSHConfig(path)
+		if err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("%s: error = %v, want one naming line 2", line, err)
+		}
+	}
+}
+
+func TestMatchHostPatterns(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		host     string
+		want     bool
+	}{
+		{[]string{"*"}, "anything", true},
+		{[]string{"web"}, "web", true},
+		{[]string{"web"}, "web1", false},
+		{[]string{"web?"}, "web1", true},
+		{[]string{"web?"}, "web", false},
+		{[]string{"*.example.com"}, "a.b.example.com", true},
+		{[]string{"*.example.com"}, "example.com", false},
+		{[]string{"a*b*c"}, "aXbYbZc", true},
+		{[]string{"a*b*c"}, "aXbYbZ", false},
+		{[]string{"db", "web"}, "web", true},
+		{[]string{"db,web"}, "web", true},
+		{[]string{"*", "!web"}, "web", false},
+		{[]string{"!web", "*"}, "web", false},
+		{[]string{"!web"}, "db", false},
+		// OpenSSH에서 [와 \는 특수 문자가 아님
+		{[]string{"web[1]"}, "web[1]", true},
+		{[]string{"web[1]"}, "web1", false},
+		{[]string{`web\*`}, `web\x`, true},
+		{nil, "web", false},
+	}
+	for _, tt := range tests {
+		if got := matchHostPatterns(tt.patterns, tt.host); got != tt.want {
+			t.Errorf("matchHostPatterns(%q, %q) = %v, want %v", tt.patterns, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestSplitSSHConfigLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		keyword string
+		args    []string
+	}{
+		{"  # comment", "", nil},
+		{"", "", nil},
+		{"HostName example.com", "hostname", []string{"example.com"}},
+		{"Port=2222", "port", []string{"2222"}},
+		{"User = deploy", "user", []string{"deploy"}},
+		{"\tHost a b\tc", "host", []string{"a", "b", "c"}},
+		{`IdentityFile "~/my keys/id"`, "identityfile", []string{"~/my keys/id"}},
+	}
+	for _, tt := range tests {
+		keyword, args := splitSSHConfigLine(tt.line)
+		if keyword != tt.keyword || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("splitSSHConfigLine(%q) = %q, %q; want %q, %q", tt.line, keyword, args, tt.keyword, tt.args)
+		}
+	}
+}
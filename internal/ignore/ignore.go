@@ -0,0 +1,224 @@
+// Package ignore decides which files an upload skips, using gitignore-style
+// patterns from .gorelayignore and the exclude/include lists of a step.
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the ignore file read from the root of an uploaded directory
+const FileName = ".gorelayignore"
+
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher holds the compiled rules for one upload root
+type Matcher struct {
+	excludes []rule // .gorelayignore followed by exclude:, last match wins
+	includes []rule // include: (empty means everything)
+}
+
+// New compiles root/.gorelayignore (if present) and the step's exclude and
+// include patterns. Paths passed to Excluded are relative to root.
+func New(root string, exclude, include []string) (*Matcher, error) {
+	m := &Matcher{}
+
+	lines, err := readIgnoreFile(filepath.Join(root, FileName))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range append(lines, exclude...) {
+		r, ok, err := compile(line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.excludes = append(m.excludes, r)
+		}
+	}
+
+	for _, pattern := range include {
+		r, ok, err := compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.includes = append(m.includes, r)
+		}
+	}
+
+	return m, nil
+}
+
+// Excluded reports whether relPath should be skipped. A path inside an
+// excluded directory is excluded too. When include patterns are set, files
+// must match one of them or lie inside a directory that does; directories
+// are always walked.
+func (m *Matcher) Excluded(relPath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+
+	relPath = strings.TrimPrefix(filepath.ToSlash(relPath), "./")
+	if relPath == "" || relPath == "." {
+		return false
+	}
+
+	// 상위 디렉토리가 제외되면 그 안의 파일도 제외 (gitignore와 동일)
+	for dir := path.Dir(relPath); dir != "."; dir = path.Dir(dir) {
+		if m.excludedSelf(dir, true) {
+			return true
+		}
+	}
+	if m.excludedSelf(relPath, isDir) {
+		return true
+	}
+
+	if isDir || len(m.includes) == 0 {
+		return false
+	}
+	return !m.included(relPath)
+}
+
+// included reports whether relPath or one of its parent directories matches
+// an include pattern, so include: [dist/] uploads everything under dist
+func (m *Matcher) included(relPath string) bool {
+	for _, r := range m.includes {
+		if r.matches(relPath, false) {
+			return true
+		}
+		for dir := path.Dir(relPath); dir != "."; dir = path.Dir(dir) {
+			if r.matches(dir, true) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *Matcher) excludedSelf(relPath string, isDir bool) bool {
+	excluded := false
+	for _, r := range m.excludes {
+		if r.matches(relPath, isDir) {
+			excluded = !r.negate
+		}
+	}
+	return excluded
+}
+
+func (r rule) matches(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	return r.re.MatchString(relPath)
+}
+
+func readIgnoreFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return lines, nil
+}
+
+// compile converts one gitignore line to a rule.
+// It returns ok=false for blank lines and comments.
+func compile(line string) (rule, bool, error) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false, nil
+	}
+
+	var r rule
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:] // \# 또는 \! 로 시작하는 이름
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule{}, false, nil
+	}
+
+	// 중간에 /가 있으면 루트 기준, 없으면 모든 깊이의 이름과 비교
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule{}, false, fmt.Errorf("invalid pattern %q: %w", line, err)
+	}
+	r.re = re
+	return r, true, nil
+}
+
+// globToRegexp translates gitignore glob syntax (*, ?, [...], **) to a regexp
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			// 0개 이상의 디렉토리
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**") && i+2 == len(glob):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
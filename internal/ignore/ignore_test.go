@@ -0,0 +1,126 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExcluded(t *testing.T) {
+	tests := []struct {
+		name     string
+		exclude  []string
+		include  []string
+		path     string
+		isDir    bool
+		excluded bool
+	}{
+		// 기본
+		{"no patterns", nil, nil, "a/b.txt", false, false},
+		{"comment and blank", []string{"# *.txt", ""}, nil, "b.txt", false, false},
+		{"escaped hash", []string{`\#notes`}, nil, "#notes", false, true},
+		{"trailing spaces ignored", []string{"*.log  "}, nil, "x.log", false, true},
+
+		// 고정 (anchoring)
+		{"name at any depth", []string{"*.log"}, nil, "a/b/c.log", false, true},
+		{"name matches directory", []string{"tmp"}, nil, "a/tmp", true, true},
+		{"leading slash anchors", []string{"/build"}, nil, "build", true, true},
+		{"leading slash not nested", []string{"/build"}, nil, "src/build", true, false},
+		{"middle slash anchors", []string{"docs/*.md"}, nil, "docs/a.md", false, true},
+		{"middle slash not nested", []string{"docs/*.md"}, nil, "x/docs/a.md", false, false},
+		{"star stays in segment", []string{"docs/*.md"}, nil, "docs/sub/a.md", false, false},
+		{"question mark", []string{"file?.txt"}, nil, "file1.txt", false, true},
+		{"question mark not slash", []string{"a?b"}, nil, "a/b", false, false},
+		{"character class", []string{"[ab].txt"}, nil, "b.txt", false, true},
+		{"negated class", []string{"[!ab].txt"}, nil, "b.txt", false, false},
+
+		// **
+		{"leading double star", []string{"**/cache"}, nil, "a/b/cache", true, true},
+		{"leading double star at root", []string{"**/cache"}, nil, "cache", true, true},
+		{"trailing double star", []string{"logs/**"}, nil, "logs/a/b.txt", false, true},
+		{"trailing double star not dir itself", []string{"logs/**"}, nil, "logs", true, false},
+		{"middle double star", []string{"a/**/z"}, nil, "a/z", false, true},
+		{"middle double star deep", []string{"a/**/z"}, nil, "a/b/c/z", false, true},
+
+		// 디렉토리 전용
+		{"dir only matches dir", []string{"build/"}, nil, "build", true, true},
+		{"dir only skips file", []string{"build/"}, nil, "build", false, false},
+		{"inside excluded dir", []string{"build/"}, nil, "build/out/app", false, true},
+		{"inside excluded nested dir", []string{"node_modules/"}, nil, "web/node_modules/x/index.js", false, true},
+
+		// 부정
+		{"negation re-includes", []string{"*.log", "!keep.log"}, nil, "keep.log", false, false},
+		{"last match wins", []string{"!keep.log", "*.log"}, nil, "keep.log", false, true},
+		{"negation inside excluded dir", []string{"build/", "!build/keep"}, nil, "build/keep", false, true},
+		{"negation of dir contents", []string{"build/*", "!build/keep"}, nil, "build/keep", false, false},
+
+		// include
+		{"include matches file", nil, []string{"*.js"}, "a/app.js", false, false},
+		{"include skips others", nil, []string{"*.js"}, "a/app.css", false, true},
+		{"include walks directories", nil, []string{"*.js"}, "a", true, false},
+		{"include dir-only pattern", nil, []string{"dist/"}, "dist/js/app.js", false, false},
+		{"include dir name", nil, []string{"dist"}, "dist/index.html", false, false},
+		{"include dir name nested", nil, []string{"dist"}, "web/dist/index.html", false, false},
+		{"include anchored dir", nil, []string{"/dist"}, "web/dist/index.html", false, true},
+		{"include double star", nil, []string{"img/**"}, "img/a/b.png", false, false},
+		{"include dir-only not file", nil, []string{"dist/"}, "dist", false, true},
+		{"include outside dir", nil, []string{"dist/"}, "src/app.js", false, true},
+		{"exclude beats include", []string{"*.map"}, []string{"dist/"}, "dist/app.js.map", false, true},
+		{"excluded dir beats include", []string{"dist/tmp/"}, []string{"dist"}, "dist/tmp/x", false, true},
+
+		// 경로 형식
+		{"dot slash prefix", []string{"/a.txt"}, nil, "./a.txt", false, true},
+		{"root never excluded", []string{"*"}, nil, ".", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(t.TempDir(), tt.exclude, tt.include)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Excluded(tt.path, tt.isDir); got != tt.excluded {
+				t.Errorf("Excluded(%q, %v) = %v, want %v (exclude %q, include %q)",
+					tt.path, tt.isDir, got, tt.excluded, tt.exclude, tt.include)
+			}
+		})
+	}
+}
+
+func TestIgnoreFile(t *testing.T) {
+	root := t.TempDir()
+	content := "# build output\n/dist\n*.log\n"
+	if err := os.WriteFile(filepath.Join(root, FileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// exclude:는 .gorelayignore 뒤에 적용되므로 !로 다시 포함 가능
+	m, err := New(root, []string{"!debug.log"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		"dist/app.js":   true,
+		"src/dist/a.js": false,
+		"x/error.log":   true,
+		"debug.log":     false,
+		"main.go":       false,
+	} {
+		if got := m.Excluded(path, false); got != want {
+			t.Errorf("Excluded(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestNilMatcher(t *testing.T) {
+	var m *Matcher
+	if m.Excluded("anything", false) {
+		t.Error("a nil matcher excluded a path")
+	}
+}
+
+func TestInvalidPattern(t *testing.T) {
+	if _, err := New(t.TempDir(), []string{"[z-a]"}, nil); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/yejune/gorelay/internal/config"
+	"github.com/yejune/gorelay/internal/ssh"
+)
+
+// hostProgress tracks how far a host got, for the interrupt summary and
+// the final report
+type hostProgress struct {
+	step     int // Current step (1-based), 0: not started
+	finished bool
+	err      error    // Error of the step that stopped the host
+	exitCode int      // Exit code of that step, -1 if it has none
+	stderr   []string // Last stderr lines of that step
+	ignored  int      // Failed steps with ignore_errors
+}
+
+// newProgress prepares an entry per server so goroutines only touch their own
+func newProgress(servers []string) map[string]*hostProgress {
+	progress := make(map[string]*hostProgress, len(servers))
+	for _, serverName := range servers {
+		progress[serverName] = &hostProgress{}
+	}
+	return progress
+}
+
+// interrupt signals every running remote command and removes remote temp files
+func (r *Runner) interrupt() {
+	r.mu.Lock()
+	r.interrupted = true
+	clients := make([]*ssh.Client, 0, len(r.clients))
+	for _, client := range r.clients {
+		clients = append(clients, client)
+	}
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(1)
+		go func(c *ssh.Client) {
+			defer wg.Done()
+			c.Interrupt()
+			c.Cleanup()
+		}(client)
+	}
+	wg.Wait()
+}
+
+// logInterrupted prints where each host stopped
+func (r *Runner) logInterrupted(task config.Task, servers []string) {
+	r.log("\n⚠ Interrupted\n")
+	for _, serverName := range servers {
+		p := r.progress[serverName]
+		switch {
+		case p == nil || p.step == 0:
+			r.log("   %s: not started\n", serverName)
+		case p.finished:
+			r.log("   %s: completed\n", serverName)
+		default:
+			script := task.Scripts[p.step-1]
+			r.log("   %s: interrupted at step %d/%d (%s)\n", serverName, p.step, len(task.Scripts), truncate(stepLabel(script), 50))
+		}
+	}
+}
+
+// stepLabel describes a script step the same way it is logged
+func stepLabel(script config.Script) string {
+	switch {
+	case script.Local != "":
+		return fmt.Sprintf("⚡ Local: %s", script.Local)
+	case script.Sync != "":
+		return fmt.Sprintf("📁 Sync: %s", script.Sync)
+	case script.Tar != "":
+		return fmt.Sprintf("📦 Tar: %s", script.Tar)
+	case script.Scp != "":
+		return fmt.Sprintf("📤 SCP: %s", script.Scp)
+	case script.Release != "":
+		return fmt.Sprintf("🚀 Release: %s", script.Release)
+	case script.Fetch != "":
+		return fmt.Sprintf("📥 Fetch: %s", script.Fetch)
+	case script.Run != "":
+		return fmt.Sprintf("▶ Run: %s", script.Run)
+	}
+	return "(empty)"
+}
@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yejune/gorelay/internal/config"
+	"golang.org/x/term"
+)
+
+// Output modes of parallel and rolling runs
+const (
+	OutputBuffered = "buffered" // Each server's output in one block, in order, once all are done
+	OutputStream   = "stream"   // Lines as they arrive, prefixed with the server name
+	OutputSummary  = "summary"  // One status line per server as it finishes
+)
+
+// 한 줄이 이보다 길면 줄바꿈 없이도 내보냄
+const maxLineLength = 64 * 1024
+
+// 서버 이름 태그 색 (빨강은 에러와 헷갈려서 제외)
+var tagColors = []string{"36", "33", "35", "32", "34", "96", "93", "95", "92", "94"}
+
+// SetOutput selects how parallel and rolling runs print server output
+func (r *Runner) SetOutput(mode string) error {
+	switch mode {
+	case "":
+		mode = OutputBuffered
+	case OutputBuffered, OutputStream, OutputSummary:
+	default:
+		return fmt.Errorf("invalid output: %s (expected buffered, stream or summary)", mode)
+	}
+	r.output = mode
+	return nil
+}
+
+// hostOutput returns the writer a server's steps print to in a parallel run,
+// and the buffer holding it when the mode keeps output until the end
+func (r *Runner) hostOutput(srvName string, index int) (io.Writer, *bytes.Buffer) {
+	if r.output != OutputStream {
+		buf := &bytes.Buffer{}
+		return &syncWriter{w: buf}, buf
+	}
+
+	color := ""
+	if useColor() {
+		color = tagColors[index%len(tagColors)]
+	}
+	return &lineWriter{emit: func(line string) {
+		r.logLine(srvName, color, line)
+	}}, nil
+}
+
+// logLine prints one streamed line with a (colored) server tag
+func (r *Runner) logLine(srvName, color, line string) {
+	line = r.prompter.Redact(line)
+
+	r.outMu.Lock()
+	defer r.outMu.Unlock()
+
+	if color != "" {
+		fmt.Printf("\033[%sm[%s]\033[0m %s\n", color, srvName, line)
+	} else {
+		fmt.Printf("[%s] %s\n", srvName, line)
+	}
+	if r.logFile != nil {
+		timestamp := time.Now().Format("2006-01-02 15:04:05")
+		r.logFile.WriteString(fmt.Sprintf("[%s] [%s] %s\n", timestamp, srvName, line))
+	}
+}
+
+// logSummary prints the status line of a finished server in summary mode
+func (r *Runner) logSummary(task config.Task, srvName string, elapsed time.Duration, err error) {
+	elapsed = elapsed.Round(100 * time.Millisecond)
+	if err == nil {
+		r.log("   ✓ %s (%s)\n", srvName, elapsed)
+		return
+	}
+
+	step := r.progress[srvName].step
+	label := truncate(stepLabel(task.Scripts[step-1]), 50)
+	r.log("   ❌ %s: step %d/%d failed (%s) after %s: %v\n", srvName, step, len(task.Scripts), label, elapsed, err)
+}
+
+// logFileOnly writes a server's output to the log file without printing it,
+// so summary mode still keeps the full output there
+func (r *Runner) logFileOnly(output string) {
+	if r.logFile == nil {
+		return
+	}
+	output = r.prompter.Redact(output)
+
+	r.outMu.Lock()
+	defer r.outMu.Unlock()
+
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	r.logFile.WriteString(fmt.Sprintf("[%s] %s\n", timestamp, strings.TrimSpace(output)))
+}
+
+// useColor reports whether the console shows ANSI colors
+func useColor() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// syncWriter serializes writes from a command's stdout and stderr copiers
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// lineWriter hands complete lines to emit, so the lines of servers running
+// at the same time never interleave mid-line
+type lineWriter struct {
+	mu   sync.Mutex
+	buf  []byte
+	emit func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(bytes.TrimSuffix(w.buf[:i], []byte("\r"))))
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) >= maxLineLength {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// Flush emits a last line that did not end with a newline
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+}
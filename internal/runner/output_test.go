@@ -0,0 +1,191 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yejune/gorelay/internal/config"
+)
+
+func TestLineWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		writes  []string
+		lines   []string // Emitted before Flush
+		flushed []string // Emitted by Flush
+	}{
+		{"whole lines", []string{"a\nb\n"}, []string{"a", "b"}, nil},
+		{"line split across writes", []string{"hel", "lo\nwor", "ld\n"}, []string{"hello", "world"}, nil},
+		{"partial last line", []string{"a\nrest"}, []string{"a"}, []string{"rest"}},
+		{"only partial", []string{"no", " newline"}, nil, []string{"no newline"}},
+		{"crlf", []string{"a\r\nb\r", "\n"}, []string{"a", "b"}, nil},
+		{"empty lines", []string{"\n\n"}, []string{"", ""}, nil},
+		{"nothing", nil, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			w := &lineWriter{emit: func(line string) { lines = append(lines, line) }}
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", s, n, err)
+				}
+			}
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("lines = %q, want %q", lines, tt.lines)
+			}
+
+			lines = nil
+			w.Flush()
+			if !reflect.DeepEqual(lines, tt.flushed) {
+				t.Errorf("flushed = %q, want %q", lines, tt.flushed)
+			}
+			// 두 번째 Flush는 아무것도 내보내지 않음
+			lines = nil
+			if w.Flush(); lines != nil {
+				t.Errorf("second Flush emitted %q", lines)
+			}
+		})
+	}
+}
+
+func TestLineWriterLongLine(t *testing.T) {
+	var lines []string
+	w := &lineWriter{emit: func(line string) { lines = append(lines, line) }}
+	w.Write(bytes.Repeat([]byte("x"), maxLineLength-1))
+	if len(lines) != 0 {
+		t.Fatalf("emitted a line before reaching maxLineLength")
+	}
+	// maxLineLength에 닿으면 줄바꿈 없이도 내보냄
+	w.Write([]byte("x"))
+	w.Write([]byte("y\n"))
+	if len(lines) != 2 || len(lines[0]) != maxLineLength || lines[1] != "y" {
+		t.Errorf("got %d line(s), want one of %d bytes and \"y\"", len(lines), maxLineLength)
+	}
+}
+
+func TestSyncWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &syncWriter{w: &buf}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				fmt.Fprintf(w, "writer %d line %d\n", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// 각 Write가 통째로 들어가므로 줄이 섞이지 않음
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 800 {
+		t.Fatalf("got %d lines, want 800", len(lines))
+	}
+	for _, line := range lines {
+		var i, j int
+		if n, _ := fmt.Sscanf(line, "writer %d line %d", &i, &j); n != 2 {
+			t.Fatalf("mixed line: %q", line)
+		}
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	r := New(&config.GorelayConfig{})
+	for _, mode := range []string{"", OutputBuffered, OutputStream, OutputSummary} {
+		if err := r.SetOutput(mode); err != nil {
+			t.Errorf("SetOutput(%q): %v", mode, err)
+		}
+	}
+	if err := r.SetOutput("quiet"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func requireShell(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+}
+
+// runLogged runs a parallel task of local steps on web1 and web2 in the
+// given output mode and returns the log file
+func runLogged(t *testing.T, mode, command string) (string, error) {
+	t.Helper()
+	logPath := filepath.Join(t.TempDir(), "gorelay.log")
+	cfg := &config.GorelayConfig{
+		Servers: map[string]config.Server{
+			"web1": {Host: "web1.example.com"},
+			"web2": {Host: "web2.example.com"},
+		},
+		Tasks: map[string]config.Task{
+			"deploy": {
+				On:       []string{"web1", "web2"},
+				Parallel: true,
+				Scripts:  []config.Script{{Local: command}},
+			},
+		},
+		Log: config.LogConfig{Enabled: true, Path: logPath},
+	}
+
+	r := New(cfg)
+	if err := r.SetOutput(mode); err != nil {
+		t.Fatal(err)
+	}
+	runErr := r.Run(context.Background(), "deploy", "")
+	r.Close()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), runErr
+}
+
+func TestOutputLogFile(t *testing.T) {
+	requireShell(t)
+	for _, mode := range []string{OutputBuffered, OutputStream, OutputSummary} {
+		t.Run(mode, func(t *testing.T) {
+			// 출력이 명령 자체(스텝 라벨)와 다르도록 산술 확장 사용
+			log, err := runLogged(t, mode, `echo "out $((1+1))"; echo "err $((2+2))" >&2`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 요약 모드도 서버별 전체 출력을 로그 파일에 남김
+			for _, want := range []string{"web1.example.com", "web2.example.com", "out 2", "err 4"} {
+				if !strings.Contains(log, want) {
+					t.Errorf("log file is missing %q:\n%s", want, log)
+				}
+			}
+			if got := strings.Count(log, "out 2"); got != 2 {
+				t.Errorf("step output logged %d time(s), want once per server:\n%s", got, log)
+			}
+		})
+	}
+}
+
+func TestSummaryLogFileKeepsFailedOutput(t *testing.T) {
+	requireShell(t)
+	log, err := runLogged(t, OutputSummary, `echo "out $((1+1))"; exit 3`)
+	if err == nil {
+		t.Fatal("expected the task to fail")
+	}
+	if got := strings.Count(log, "out 2"); got != 2 {
+		t.Errorf("failed servers' output logged %d time(s), want 2:\n%s", got, log)
+	}
+	if !strings.Contains(log, "step 1/1 failed") {
+		t.Errorf("log file is missing the summary line:\n%s", log)
+	}
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/yejune/gorelay/internal/config"
+)
+
+// 동시에 진행하는 SSH 핸드셰이크 기본 상한 (sshd MaxStartups 기본값의 시작점)
+const defaultDialLimit = 10
+
+// SetForks limits how many servers run at once (--forks), overriding the
+// task's concurrency setting. 0 keeps the task setting.
+func (r *Runner) SetForks(n int) {
+	r.forks = n
+}
+
+// setLimit decides how many servers of the task run at once and sizes the
+// dial slots to match
+func (r *Runner) setLimit(task config.Task) error {
+	if task.Concurrency < 0 {
+		return fmt.Errorf("concurrency cannot be negative")
+	}
+	if task.Concurrency > 0 && !task.Parallel && task.Strategy == "" {
+		return fmt.Errorf("concurrency requires parallel: true or strategy: rolling")
+	}
+
+	r.limit = task.Concurrency
+	if r.forks > 0 {
+		r.limit = r.forks
+	}
+
+	dialLimit := defaultDialLimit
+	if r.limit > 0 {
+		dialLimit = r.limit
+	}
+	r.dials = make(chan struct{}, dialLimit)
+	return nil
+}
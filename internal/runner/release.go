@@ -0,0 +1,201 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yejune/gorelay/internal/config"
+	"github.com/yejune/gorelay/internal/ssh"
+)
+
+// RollbackOptions selects which release the rollback command switches to
+type RollbackOptions struct {
+	Task   string // Task with the release step (needed when several have one)
+	Server string // Only this server (--on)
+	To     string // Release name (default: the one before current)
+	Force  bool   // Allow a To that is not in releases.log
+	List   bool   // Only list releases
+}
+
+// releaseOptions builds the settings of a release step. The name is the
+// same on every server of a run.
+func (r *Runner) releaseOptions(script config.Script) (ssh.ReleaseOptions, error) {
+	upload, err := uploadOptions(script)
+	if err != nil {
+		return ssh.ReleaseOptions{}, err
+	}
+
+	var name string
+	switch script.ReleaseName {
+	case "", "timestamp":
+		started := r.startedAt
+		if started.IsZero() {
+			started = time.Now()
+		}
+		name = started.UTC().Format("20060102150405")
+	case "git":
+		out, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+		if err != nil {
+			return ssh.ReleaseOptions{}, fmt.Errorf("release_name: git: failed to read commit: %w", err)
+		}
+		name = strings.TrimSpace(string(out))
+	default:
+		return ssh.ReleaseOptions{}, fmt.Errorf("invalid release_name: %s (expected timestamp or git)", script.ReleaseName)
+	}
+
+	return ssh.ReleaseOptions{
+		Upload: upload,
+		Name:   name,
+		Shared: script.Shared,
+		Keep:   script.Keep,
+	}, nil
+}
+
+// Rollback lists the releases of the task's release step on each server and
+// switches current to the previous release (or opts.To).
+func (r *Runner) Rollback(ctx context.Context, opts RollbackOptions) error {
+	taskName, baseDir, err := r.findRelease(opts.Task)
+	if err != nil {
+		return err
+	}
+
+	servers := r.taskServers(r.config.Tasks[taskName], opts.Server)
+	if opts.List {
+		r.log("📋 Releases in %s (task: %s)\n", baseDir, taskName)
+	} else {
+		r.log("⏪ Rollback %s (task: %s)\n", baseDir, taskName)
+	}
+
+	for _, serverName := range servers {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		server, ok := r.config.Servers[serverName]
+		if !ok {
+			return fmt.Errorf("server '%s' not found", serverName)
+		}
+		r.log("\n📡 [%s] %s\n", serverName, getHost(server))
+
+		client, err := r.getClient(serverName, server)
+		if err != nil {
+			return fmt.Errorf("[%s] %w", serverName, err)
+		}
+		releases, err := client.Releases(baseDir)
+		if err != nil {
+			return fmt.Errorf("[%s] %w", serverName, err)
+		}
+		if len(releases) == 0 {
+			return fmt.Errorf("[%s] no releases in %s/releases", serverName, baseDir)
+		}
+
+		for _, rel := range releases {
+			marker := "  "
+			if rel.Current {
+				marker = "→ "
+			}
+			note := ""
+			if !rel.Logged {
+				note = " (not in releases.log)"
+			}
+			r.log("    %s%s%s\n", marker, rel.Name, note)
+		}
+		if opts.List {
+			continue
+		}
+
+		target, err := rollbackTarget(releases, opts.To, opts.Force)
+		if err != nil {
+			return fmt.Errorf("[%s] %w", serverName, err)
+		}
+
+		if err := client.SwitchRelease(baseDir, target); err != nil {
+			r.log("   ❌ Error: %v\n", err)
+			return fmt.Errorf("[%s] %w", serverName, err)
+		}
+		r.log("   ✓ current → releases/%s\n", target)
+	}
+
+	if !opts.List {
+		r.log("\n✅ Rollback completed (restart services if they do not follow the current link)\n")
+	}
+	return nil
+}
+
+// rollbackTarget returns the release to switch to: to, which must be a
+// finished deployment unless force is set, or else the previous release
+func rollbackTarget(releases []ssh.Release, to string, force bool) (string, error) {
+	if to == "" {
+		return previousRelease(releases)
+	}
+	for _, rel := range releases {
+		if rel.Name != to {
+			continue
+		}
+		// 로그에 없는 디렉토리는 업로드가 끝나지 않았을 수 있음
+		if !rel.Logged && !force {
+			return "", fmt.Errorf("release %s is not in releases.log (it may be an unfinished upload); use --force to switch to it anyway", to)
+		}
+		return to, nil
+	}
+	return "", fmt.Errorf("release %s not found", to)
+}
+
+// previousRelease returns the release deployed just before the current one.
+// Directories missing from releases.log (such as unfinished uploads) are skipped.
+func previousRelease(releases []ssh.Release) (string, error) {
+	for i, rel := range releases {
+		if !rel.Current {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if releases[j].Logged {
+				return releases[j].Name, nil
+			}
+		}
+		return "", fmt.Errorf("no release older than %s", rel.Name)
+	}
+	return "", fmt.Errorf("current does not point to a release; use --to=<release>")
+}
+
+// findRelease locates the release step rollback works on
+func (r *Runner) findRelease(taskName string) (string, string, error) {
+	var names []string
+	for name := range r.config.Tasks {
+		if taskName == "" || name == taskName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var found []string
+	var baseDir string
+	for _, name := range names {
+		for _, script := range r.config.Tasks[name].Scripts {
+			if script.Release == "" {
+				continue
+			}
+			_, base, err := parseUploadPath(script.Release)
+			if err != nil {
+				return "", "", err
+			}
+			found = append(found, name)
+			baseDir = base
+			break
+		}
+	}
+
+	switch {
+	case len(found) == 1:
+		return found[0], baseDir, nil
+	case len(found) == 0 && taskName != "":
+		return "", "", fmt.Errorf("task '%s' has no release step", taskName)
+	case len(found) == 0:
+		return "", "", fmt.Errorf("no task has a release step")
+	default:
+		return "", "", fmt.Errorf("several tasks have release steps (%s); choose one with --task=<name>", strings.Join(found, ", "))
+	}
+}
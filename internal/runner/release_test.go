@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yejune/gorelay/internal/ssh"
+)
+
+func TestRollbackTarget(t *testing.T) {
+	releases := []ssh.Release{
+		{Name: "1", Logged: true},
+		{Name: "2", Logged: false}, // 끝나지 않은 업로드
+		{Name: "3", Logged: true, Current: true},
+	}
+
+	tests := []struct {
+		name    string
+		to      string
+		force   bool
+		want    string
+		wantErr string
+	}{
+		{name: "previous skips unlogged", want: "1"},
+		{name: "logged", to: "1", want: "1"},
+		{name: "current", to: "3", want: "3"},
+		{name: "unlogged", to: "2", wantErr: "--force"},
+		{name: "unlogged with force", to: "2", force: true, want: "2"},
+		{name: "missing", to: "9", force: true, wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		got, err := rollbackTarget(releases, tt.to, tt.force)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: rollbackTarget = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/yejune/gorelay/internal/config"
+	"github.com/yejune/gorelay/internal/ssh"
+)
+
+// 리포트에 남기는 stderr 마지막 줄 수
+const reportStderrLines = 5
+
+// needsReport reports whether a server failed or ignored an error, which is
+// when the final report is printed
+func (r *Runner) needsReport(servers []string) bool {
+	for _, serverName := range servers {
+		if p := r.progress[serverName]; p != nil && (p.err != nil || p.ignored > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// logReport prints the status of every server: failed step, exit code and
+// the last lines of stderr for failures
+func (r *Runner) logReport(task config.Task, servers []string) {
+	width := 0
+	for _, serverName := range servers {
+		width = max(width, len(serverName))
+	}
+
+	r.log("\n📋 Report\n")
+	for _, serverName := range servers {
+		p := r.progress[serverName]
+		switch {
+		case p == nil || p.step == 0:
+			r.log("   - %-*s  not started\n", width, serverName)
+		case p.err != nil:
+			status := fmt.Sprintf("failed at step %d/%d (%s)", p.step, len(task.Scripts), truncate(stepLabel(task.Scripts[p.step-1]), 50))
+			if p.exitCode >= 0 {
+				status += fmt.Sprintf(", exit code %d", p.exitCode)
+			}
+			r.log("   ❌ %-*s  %s\n", width, serverName, status)
+			for _, line := range p.stderr {
+				r.log("        stderr: %s\n", line)
+			}
+		case p.finished && p.ignored > 0:
+			r.log("   ⚠ %-*s  ok, %d error(s) ignored\n", width, serverName, p.ignored)
+		case p.finished:
+			r.log("   ✓ %-*s  ok\n", width, serverName)
+		default:
+			r.log("   - %-*s  stopped at step %d/%d\n", width, serverName, p.step, len(task.Scripts))
+		}
+	}
+}
+
+// logIgnored reports a failed step that has ignore_errors
+func (r *Runner) logIgnored(w io.Writer, err error) {
+	msg := r.prompter.Redact(fmt.Sprintf("%v", err))
+	fmt.Fprintf(w, "   ⚠ Error ignored: %s\n", msg)
+	if r.logFile != nil {
+		timestamp := time.Now().Format("2006-01-02 15:04:05")
+		r.logFile.WriteString(fmt.Sprintf("[%s] ⚠ Error ignored: %s\n", timestamp, msg))
+	}
+}
+
+// exitCode returns the exit code of a failed local or remote command, or -1
+func exitCode(err error) int {
+	if code, ok := ssh.ExitStatus(err); ok {
+		return code
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// tailWriter passes stderr through and keeps its end for the report
+type tailWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+// 마지막 몇 줄만 필요하므로 이만큼만 보관
+const tailBytes = 4096
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > 2*tailBytes {
+		t.buf = append([]byte(nil), t.buf[len(t.buf)-tailBytes:]...)
+	}
+	return t.w.Write(p)
+}
+
+// lines returns the last non-empty stderr lines
+func (t *tailWriter) lines() []string {
+	var lines []string
+	for _, line := range strings.Split(string(bytes.TrimSpace(t.buf)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > reportStderrLines {
+		lines = lines[len(lines)-reportStderrLines:]
+	}
+	return lines
+}
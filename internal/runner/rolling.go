@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yejune/gorelay/internal/config"
+)
+
+// rollingBatches splits servers into the batches of a rolling task.
+// It returns nil for tasks without strategy: rolling.
+func rollingBatches(task config.Task, servers []string) ([][]string, error) {
+	switch task.Strategy {
+	case "":
+		if task.Batch != "" || task.MaxFail != 0 || task.Pause != 0 {
+			return nil, fmt.Errorf("batch, max_fail and pause require strategy: rolling")
+		}
+		return nil, nil
+	case "rolling":
+	default:
+		return nil, fmt.Errorf("invalid strategy: %s (expected rolling)", task.Strategy)
+	}
+	if task.ContinueOnError {
+		return nil, fmt.Errorf("continue_on_error cannot be combined with strategy: rolling (use max_fail)")
+	}
+	if task.MaxFail < 0 || task.Pause < 0 {
+		return nil, fmt.Errorf("max_fail and pause cannot be negative")
+	}
+
+	size, err := batchSize(task.Batch, len(servers))
+	if err != nil {
+		return nil, err
+	}
+
+	var batches [][]string
+	for start := 0; start < len(servers); start += size {
+		end := min(start+size, len(servers))
+		batches = append(batches, servers[start:end])
+	}
+	return batches, nil
+}
+
+// batchSize resolves "5" or "20%" against the number of servers.
+// A percentage rounds up, so every batch has at least one server.
+func batchSize(batch string, total int) (int, error) {
+	batch = strings.TrimSpace(batch)
+	if batch == "" {
+		return 1, nil
+	}
+
+	if percent, ok := strings.CutSuffix(batch, "%"); ok {
+		p, err := strconv.Atoi(strings.TrimSpace(percent))
+		if err != nil || p <= 0 || p > 100 {
+			return 0, fmt.Errorf("invalid batch: %s (expected a count like 5 or a percentage like 20%%)", batch)
+		}
+		return max((total*p+99)/100, 1), nil
+	}
+
+	n, err := strconv.Atoi(batch)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid batch: %s (expected a count like 5 or a percentage like 20%%)", batch)
+	}
+	return n, nil
+}
+
+// runRolling runs the task one batch at a time, the servers of a batch in
+// parallel. Once more than task.MaxFail servers have failed the remaining
+// batches are skipped.
+func (r *Runner) runRolling(ctx context.Context, task config.Task, batches [][]string) error {
+	var errs []error
+	var failedServers []string
+	completed := 0
+
+	for i, batch := range batches {
+		if i > 0 && task.Pause > 0 {
+			r.log("\n⏸ Pausing %s before batch %d/%d\n", task.Pause, i+1, len(batches))
+			select {
+			case <-ctx.Done():
+			case <-time.After(task.Pause):
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		r.log("\n🔁 Batch %d/%d: %s\n", i+1, len(batches), strings.Join(batch, ", "))
+		failed, err := r.runBatch(ctx, task, batch)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// 배치 요약
+		var batchFailed []string
+		for _, serverName := range batch {
+			if err, ok := failed[serverName]; ok {
+				batchFailed = append(batchFailed, serverName)
+				errs = append(errs, err)
+			}
+		}
+		completed += len(batch) - len(batchFailed)
+		failedServers = append(failedServers, batchFailed...)
+		if len(batchFailed) == 0 {
+			r.log("\n   ✓ Batch %d/%d: %d/%d succeeded\n", i+1, len(batches), len(batch), len(batch))
+		} else {
+			r.log("\n   ❌ Batch %d/%d: %d/%d succeeded, failed: %s\n", i+1, len(batches), len(batch)-len(batchFailed), len(batch), strings.Join(batchFailed, ", "))
+		}
+
+		// 허용치를 넘으면 남은 배치는 시작하지 않음
+		if len(errs) > task.MaxFail {
+			skipped := 0
+			for _, rest := range batches[i+1:] {
+				skipped += len(rest)
+			}
+			r.log("\n❌ Rolling deploy aborted: %d server(s) failed (max_fail: %d), %d completed, %d not started\n", len(errs), task.MaxFail, completed, skipped)
+			return errors.Join(errs...)
+		}
+	}
+
+	if len(errs) > 0 {
+		r.log("\n⚠ Rolling deploy finished: %d server(s) completed, %d failed (max_fail: %d): %s\n", completed, len(errs), task.MaxFail, strings.Join(failedServers, ", "))
+		return errors.Join(errs...)
+	}
+
+	r.log("\n✅ All %d servers completed in %d batch(es)\n", completed, len(batches))
+	return nil
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yejune/gorelay/internal/config"
+)
+
+func TestBatchSize(t *testing.T) {
+	tests := []struct {
+		batch   string
+		total   int
+		want    int
+		wantErr bool
+	}{
+		// 개수
+		{"", 5, 1, false},
+		{"1", 5, 1, false},
+		{"3", 5, 3, false},
+		{" 2 ", 5, 2, false},
+		{"10", 3, 10, false}, // 서버 수보다 커도 됨 (한 배치)
+
+		// 퍼센트는 올림
+		{"20%", 10, 2, false},
+		{"25%", 10, 3, false},
+		{"1%", 10, 1, false},
+		{"50%", 3, 2, false},
+		{"100%", 7, 7, false},
+		{"10%", 0, 1, false},
+		{" 30 % ", 10, 3, false},
+
+		// 잘못된 값
+		{"0", 5, 0, true},
+		{"-1", 5, 0, true},
+		{"0%", 5, 0, true},
+		{"101%", 5, 0, true},
+		{"150%", 5, 0, true},
+		{"abc", 5, 0, true},
+		{"%", 5, 0, true},
+		{"2.5", 5, 0, true},
+		{"5x", 5, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := batchSize(tt.batch, tt.total)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("batchSize(%q, %d) = %d, %v; want %d (error: %v)", tt.batch, tt.total, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRollingBatches(t *testing.T) {
+	servers := []string{"a", "b", "c", "d", "e"}
+
+	tests := []struct {
+		name    string
+		task    config.Task
+		want    [][]string
+		wantErr string
+	}{
+		{name: "not rolling", task: config.Task{}, want: nil},
+		{name: "default batch", task: config.Task{Strategy: "rolling"},
+			want: [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{name: "count", task: config.Task{Strategy: "rolling", Batch: "2"},
+			want: [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{name: "percentage rounds up", task: config.Task{Strategy: "rolling", Batch: "50%"},
+			want: [][]string{{"a", "b", "c"}, {"d", "e"}}},
+		{name: "larger than host count", task: config.Task{Strategy: "rolling", Batch: "9"},
+			want: [][]string{{"a", "b", "c", "d", "e"}}},
+		{name: "invalid batch", task: config.Task{Strategy: "rolling", Batch: "0%"}, wantErr: "invalid batch"},
+		{name: "invalid strategy", task: config.Task{Strategy: "canary"}, wantErr: "invalid strategy"},
+		{name: "batch without rolling", task: config.Task{Batch: "2"}, wantErr: "require strategy: rolling"},
+		{name: "pause without rolling", task: config.Task{Pause: time.Second}, wantErr: "require strategy: rolling"},
+		{name: "continue_on_error", task: config.Task{Strategy: "rolling", ContinueOnError: true}, wantErr: "max_fail"},
+		{name: "negative max_fail", task: config.Task{Strategy: "rolling", MaxFail: -1}, wantErr: "negative"},
+	}
+	for _, tt := range tests {
+		got, err := rollingBatches(tt.task, servers)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: rollingBatches = %v, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
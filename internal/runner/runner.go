@@ -2,10 +2,14 @@ package runner
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,22 +18,43 @@ import (
 	"github.com/yejune/gorelay/internal/ssh"
 )
 
+// 끊긴 연결을 다시 맺을 때 기본 시도 횟수
+const defaultReconnect = 3
+
 type Runner struct {
-	config  *config.GorelayConfig
-	clients map[string]*ssh.Client
-	mu      sync.Mutex
-	stdout  io.Writer
-	stderr  io.Writer
-	verbose bool
-	logFile *os.File
+	config   *config.GorelayConfig
+	clients  map[string]*ssh.Client
+	opened   []string      // Keys of clients in the order they were connected
+	prompter *ssh.Prompter // 키 암호는 실행 전체에서 한 번만 물어봄
+	mu       sync.Mutex
+	stdout   io.Writer
+	stderr   io.Writer
+	verbose  bool
+	logFile  *os.File
+	progress map[string]*hostProgress // Per-host step progress of the current run
+
+	startedAt time.Time // Start of the current run (shared release timestamp)
+
+	forks       int                     // --forks: servers running at once, overrides task concurrency
+	limit       int                     // Servers running at once in the current run (0: no limit)
+	dials       chan struct{}           // Slots for SSH handshakes in progress
+	dialing     map[string]*pendingDial // Connections being dialed
+	interrupted bool                    // Set by interrupt; late connections are interrupted too
+
+	output string     // Output mode of parallel runs (buffered, stream, summary)
+	outMu  sync.Mutex // Keeps console and log file lines whole
 }
 
 func New(cfg *config.GorelayConfig) *Runner {
 	r := &Runner{
-		config:  cfg,
-		clients: make(map[string]*ssh.Client),
-		stdout:  os.Stdout,
-		stderr:  os.Stderr,
+		config:   cfg,
+		clients:  make(map[string]*ssh.Client),
+		prompter: ssh.NewPrompter(),
+		stdout:   os.Stdout,
+		stderr:   os.Stderr,
+		dials:    make(chan struct{}, defaultDialLimit),
+		dialing:  make(map[string]*pendingDial),
+		output:   OutputBuffered,
 	}
 
 	// 로그 파일 설정
@@ -56,8 +81,16 @@ func (r *Runner) SetVerbose(v bool) {
 }
 
 func (r *Runner) Close() {
-	for _, client := range r.clients {
+	// 점프 호스트는 그것을 거치는 연결보다 먼저 열리므로 역순으로 닫음
+	for i := len(r.opened) - 1; i >= 0; i-- {
+		key := r.opened[i]
+		client, ok := r.clients[key]
+		if !ok {
+			continue
+		}
+		client.Cleanup()
 		client.Close()
+		delete(r.clients, key)
 	}
 	if r.logFile != nil {
 		r.logFile.Close()
@@ -65,7 +98,10 @@ func (r *Runner) Close() {
 }
 
 func (r *Runner) log(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
+	msg := r.prompter.Redact(fmt.Sprintf(format, args...))
+
+	r.outMu.Lock()
+	defer r.outMu.Unlock()
 
 	// 콘솔 출력
 	fmt.Print(msg)
@@ -79,44 +115,77 @@ func (r *Runner) log(format string, args ...interface{}) {
 	}
 }
 
-func (r *Runner) Run(taskName string, serverFilter string) error {
+// Run executes a task. Cancelling ctx signals running remote commands,
+// removes remote temp files and stops before the next step.
+func (r *Runner) Run(ctx context.Context, taskName string, serverFilter string) error {
 	task, ok := r.config.Tasks[taskName]
 	if !ok {
 		return fmt.Errorf("task '%s' not found", taskName)
 	}
 
-	// 서버 목록 결정
-	var servers []string
-	if serverFilter != "" {
-		servers = []string{serverFilter}
-	} else if len(task.On) > 0 {
-		servers = task.On
-	} else {
-		// 기본 서버 사용
-		for name := range r.config.Servers {
-			servers = append(servers, name)
-			break
-		}
+	servers := r.taskServers(task, serverFilter)
+
+	batches, err := rollingBatches(task, servers)
+	if err != nil {
+		return fmt.Errorf("task '%s': %w", taskName, err)
+	}
+	if err := r.setLimit(task); err != nil {
+		return fmt.Errorf("task '%s': %w", taskName, err)
 	}
 
-	// 배열 host를 가진 서버는 자동 확장
-	servers = r.config.GetExpandedServers(servers)
+	// PTY는 로컬 터미널 하나에 연결되므로 병렬 실행 불가
+	concurrent := task.Parallel && len(servers) > 1
+	if batches != nil {
+		concurrent = len(batches[0]) > 1
+	}
+	if concurrent {
+		for _, script := range task.Scripts {
+			if script.TTY {
+				return fmt.Errorf("task '%s': tty steps cannot run in parallel", taskName)
+			}
+		}
+	}
 
+	var mode []string
+	if batches != nil {
+		mode = append(mode, fmt.Sprintf("rolling, %d batch(es) of up to %d", len(batches), len(batches[0])))
+	} else if concurrent {
+		mode = append(mode, "parallel")
+	}
+	if concurrent && r.limit > 0 && r.limit < len(servers) {
+		mode = append(mode, fmt.Sprintf("%d at a time", r.limit))
+	}
 	r.log("🚀 Running task: %s", taskName)
-	if task.Parallel && len(servers) > 1 {
-		r.log(" (parallel)")
+	if len(mode) > 0 {
+		r.log(" (%s)", strings.Join(mode, ", "))
 	}
 	r.log("\n")
 
 	startTime := time.Now()
-
-	var err error
-	// 병렬 실행
-	if task.Parallel && len(servers) > 1 {
-		err = r.runParallel(task, servers)
-	} else {
+	r.startedAt = startTime
+	r.progress = newProgress(servers)
+
+	// 취소되면 원격 세션에 시그널 전달 후 임시 파일 정리
+	interrupted := make(chan struct{})
+	stopInterrupt := context.AfterFunc(ctx, func() {
+		r.interrupt()
+		close(interrupted)
+	})
+
+	switch {
+	case batches != nil:
+		// 배치 단위 롤링 실행
+		err = r.runRolling(ctx, task, batches)
+	case concurrent:
+		// 병렬 실행
+		err = r.runParallel(ctx, task, servers)
+	default:
 		// 순차 실행
-		err = r.runSequential(task, servers)
+		err = r.runSequential(ctx, task, servers)
+	}
+
+	if !stopInterrupt() {
+		<-interrupted
 	}
 
 	elapsed := time.Since(startTime)
@@ -124,101 +193,229 @@ func (r *Runner) Run(taskName string, serverFilter string) error {
 		r.log("   ⏱ Elapsed: %s\n", elapsed.Round(time.Millisecond))
 	}
 
+	if ctx.Err() != nil {
+		r.logInterrupted(task, servers)
+		return fmt.Errorf("task '%s' interrupted", taskName)
+	}
+	if r.needsReport(servers) {
+		r.logReport(task, servers)
+	}
 	return err
 }
 
-func (r *Runner) runSequential(task config.Task, servers []string) error {
+// taskServers returns the (expanded) server names a task runs on
+func (r *Runner) taskServers(task config.Task, serverFilter string) []string {
+	var servers []string
+	if serverFilter != "" {
+		servers = []string{serverFilter}
+	} else if len(task.On) > 0 {
+		servers = task.On
+	} else {
+		// 기본 서버 사용
+		for name := range r.config.Servers {
+			servers = append(servers, name)
+			break
+		}
+	}
+
+	// 배열 host를 가진 서버는 자동 확장
+	return r.config.GetExpandedServers(servers)
+}
+
+func (r *Runner) runSequential(ctx context.Context, task config.Task, servers []string) error {
+	var errs []error
 	for _, serverName := range servers {
 		server, ok := r.config.Servers[serverName]
 		if !ok {
 			return fmt.Errorf("server '%s' not found", serverName)
 		}
 
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
 		host := getHost(server)
 		r.log("\n📡 [%s] %s\n", serverName, host)
 
-		for _, script := range task.Scripts {
-			if err := r.runScript(serverName, server, script, r.stdout, r.stderr); err != nil {
-				r.log("   ❌ Error: %v\n", err)
-				return fmt.Errorf("[%s] script failed: %w", serverName, err)
+		err := r.runHost(ctx, task, serverName, server, r.stdout, r.stderr)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			r.log("   ❌ Error: %v\n", err)
+			errs = append(errs, fmt.Errorf("[%s] script failed: %w", serverName, err))
+			// continue_on_error가 아니면 나머지 서버는 시작하지 않음
+			if !task.ContinueOnError {
+				break
 			}
 		}
 	}
 
+	if len(errs) > 0 {
+		r.log("\n❌ %d server(s) failed\n", len(errs))
+		return errors.Join(errs...)
+	}
+
 	r.log("\n✅ Task completed\n")
 	return nil
 }
 
-func (r *Runner) runParallel(task config.Task, servers []string) error {
+func (r *Runner) runParallel(ctx context.Context, task config.Task, servers []string) error {
+	failed, err := r.runBatch(ctx, task, servers)
+	if err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		r.log("\n❌ %d server(s) failed\n", len(failed))
+		return joinErrors(servers, failed)
+	}
+
+	r.log("\n✅ All %d servers completed\n", len(servers))
+	return nil
+}
+
+// runBatch runs the task on the servers in parallel, at most r.limit at a
+// time, and prints their output according to r.output. It returns the
+// errors of the failed servers.
+func (r *Runner) runBatch(ctx context.Context, task config.Task, servers []string) (map[string]error, error) {
+	for _, serverName := range servers {
+		if _, ok := r.config.Servers[serverName]; !ok {
+			return nil, fmt.Errorf("server '%s' not found", serverName)
+		}
+	}
+
 	var wg sync.WaitGroup
-	errCh := make(chan error, len(servers))
+	failed := make(map[string]error)
 	results := make(map[string]*bytes.Buffer)
 	var resultsMu sync.Mutex
 
-	for _, serverName := range servers {
-		server, ok := r.config.Servers[serverName]
-		if !ok {
-			return fmt.Errorf("server '%s' not found", serverName)
-		}
+	workers := len(servers)
+	if r.limit > 0 && r.limit < workers {
+		workers = r.limit
+	}
 
+	// 서버 수와 상관없이 workers개의 고루틴만 실행
+	queue := make(chan int)
+	for w := 0; w < workers; w++ {
 		wg.Add(1)
-		go func(srvName string, srv config.Server) {
+		go func() {
 			defer wg.Done()
+			for i := range queue {
+				srvName := servers[i]
+				srv := r.config.Servers[srvName]
+				startTime := time.Now()
+
+				out, buf := r.hostOutput(srvName, i)
+				if r.output == OutputStream {
+					fmt.Fprintf(out, "📡 %s\n", getHost(srv))
+				} else {
+					fmt.Fprintf(out, "\n📡 [%s] %s\n", srvName, getHost(srv))
+				}
+				err := r.runHost(ctx, task, srvName, srv, out, out)
+				if err != nil && ctx.Err() == nil {
+					fmt.Fprintf(out, "   ❌ Error: %v\n", err)
+				} else if ctx.Err() == nil {
+					fmt.Fprintf(out, "   ✓ Done\n")
+				}
+				if r.output == OutputSummary {
+					if ctx.Err() == nil {
+						r.logSummary(task, srvName, time.Since(startTime), err)
+					}
+					r.logFileOnly(buf.String())
+				}
 
-			// 각 서버별 출력 버퍼
-			buf := &bytes.Buffer{}
-			buf.WriteString(fmt.Sprintf("\n📡 [%s] %s\n", srvName, getHost(srv)))
-
-			for _, script := range task.Scripts {
-				if err := r.runScript(srvName, srv, script, buf, buf); err != nil {
-					buf.WriteString(fmt.Sprintf("   ❌ Error: %v\n", err))
-					errCh <- fmt.Errorf("[%s] %w", srvName, err)
-					resultsMu.Lock()
+				resultsMu.Lock()
+				if err != nil {
+					failed[srvName] = fmt.Errorf("[%s] %w", srvName, err)
+				}
+				if buf != nil {
 					results[srvName] = buf
-					resultsMu.Unlock()
-					return
 				}
+				resultsMu.Unlock()
 			}
-
-			buf.WriteString(fmt.Sprintf("   ✓ Done\n"))
-			resultsMu.Lock()
-			results[srvName] = buf
-			resultsMu.Unlock()
-		}(serverName, server)
+		}()
 	}
 
+	for i := range servers {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- i
+	}
+	close(queue)
 	wg.Wait()
-	close(errCh)
 
 	// 결과 출력 (순서대로)
-	for _, serverName := range servers {
-		if buf, ok := results[serverName]; ok {
-			r.log("%s", buf.String())
+	if r.output == OutputBuffered {
+		for _, serverName := range servers {
+			if buf, ok := results[serverName]; ok {
+				r.log("%s", buf.String())
+			}
 		}
 	}
 
-	// 에러 수집
-	var errs []error
-	for err := range errCh {
-		errs = append(errs, err)
-	}
+	return failed, nil
+}
 
-	if len(errs) > 0 {
-		r.log("\n❌ %d server(s) failed\n", len(errs))
-		return errs[0]
+// runHost runs all steps of the task on one server. A failed step stops the
+// server unless it has ignore_errors; the failure is kept for the report.
+func (r *Runner) runHost(ctx context.Context, task config.Task, srvName string, srv config.Server, stdout, stderr io.Writer) error {
+	progress := r.progress[srvName]
+	for i, script := range task.Scripts {
+		if ctx.Err() != nil {
+			return nil
+		}
+		progress.step = i + 1
+
+		tail := &tailWriter{w: stderr}
+		err := r.runScript(ctx, srvName, srv, script, stdout, tail)
+		// 줄바꿈 없이 끝난 출력이 다음 줄과 섞이지 않도록
+		if lw, ok := stdout.(*lineWriter); ok {
+			lw.Flush()
+		}
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if script.IgnoreErrors {
+			progress.ignored++
+			r.logIgnored(stdout, err)
+			continue
+		}
+		progress.err = err
+		progress.exitCode = exitCode(err)
+		progress.stderr = tail.lines()
+		return err
 	}
 
-	r.log("\n✅ All %d servers completed\n", len(servers))
+	if ctx.Err() == nil {
+		progress.finished = true
+	}
 	return nil
 }
 
-func (r *Runner) runScript(serverName string, server config.Server, script config.Script, stdout, stderr io.Writer) error {
+// joinErrors combines the errors of the failed servers in run order
+func joinErrors(servers []string, failed map[string]error) error {
+	var errs []error
+	for _, serverName := range servers {
+		if err, ok := failed[serverName]; ok {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Runner) runScript(ctx context.Context, serverName string, server config.Server, script config.Script, stdout, stderr io.Writer) error {
 	startTime := time.Now()
 
 	// 로컬 실행
 	if script.Local != "" {
 		r.logScript(stdout, "⚡ Local", script.Local)
-		err := r.runLocal(script.Local, stdout, stderr)
+		err := r.runLocal(ctx, script.Local, stdout, stderr)
 		r.logElapsed(stdout, startTime)
 		return err
 	}
@@ -229,14 +426,19 @@ func (r *Runner) runScript(serverName string, server config.Server, script confi
 		if err != nil {
 			return err
 		}
+		opts, err := uploadOptions(script)
+		if err != nil {
+			return err
+		}
 		client, err := r.getClient(serverName, server)
 		if err != nil {
 			return err
 		}
 		r.logScript(stdout, "📁 Sync", fmt.Sprintf("%s → %s", localPath, remotePath))
-		uploaded, uploadErr := client.UploadSync(localPath, remotePath)
+		result, uploadErr := client.UploadSync(localPath, remotePath, opts)
 		if uploadErr == nil {
-			fmt.Fprintf(stdout, "      %d file(s) uploaded\n", uploaded)
+			fmt.Fprintf(stdout, "      %d file(s) uploaded\n", result.Uploaded)
+			logDeleted(stdout, result)
 		}
 		r.logElapsed(stdout, startTime)
 		return uploadErr
@@ -248,12 +450,16 @@ func (r *Runner) runScript(serverName string, server config.Server, script confi
 		if err != nil {
 			return err
 		}
+		opts, err := uploadOptions(script)
+		if err != nil {
+			return err
+		}
 		client, err := r.getClient(serverName, server)
 		if err != nil {
 			return err
 		}
 		r.logScript(stdout, "📦 Tar", fmt.Sprintf("%s → %s", localPath, remotePath))
-		err = client.UploadTar(localPath, remotePath)
+		err = client.UploadTar(localPath, remotePath, opts)
 		r.logElapsed(stdout, startTime)
 		return err
 	}
@@ -264,12 +470,16 @@ func (r *Runner) runScript(serverName string, server config.Server, script confi
 		if err != nil {
 			return err
 		}
+		opts, err := uploadOptions(script)
+		if err != nil {
+			return err
+		}
 		client, err := r.getClient(serverName, server)
 		if err != nil {
 			return err
 		}
 		r.logScript(stdout, "📤 SCP", fmt.Sprintf("%s → %s", localPath, remotePath))
-		uploaded, uploadErr := client.UploadSCP(localPath, remotePath)
+		uploaded, uploadErr := client.UploadSCP(localPath, remotePath, opts)
 		if uploadErr == nil {
 			fmt.Fprintf(stdout, "      %d file(s) uploaded\n", uploaded)
 		}
@@ -277,6 +487,52 @@ func (r *Runner) runScript(serverName string, server config.Server, script confi
 		return uploadErr
 	}
 
+	// release: releases/<이름>에 올리고 current 링크 전환
+	if script.Release != "" {
+		localPath, baseDir, err := parseUploadPath(script.Release)
+		if err != nil {
+			return err
+		}
+		opts, err := r.releaseOptions(script)
+		if err != nil {
+			return err
+		}
+		client, err := r.getClient(serverName, server)
+		if err != nil {
+			return err
+		}
+		r.logScript(stdout, "🚀 Release", fmt.Sprintf("%s → %s/releases/%s", localPath, baseDir, opts.Name))
+		result, releaseErr := client.DeployRelease(localPath, baseDir, opts)
+		if releaseErr == nil {
+			fmt.Fprintf(stdout, "      current → releases/%s\n", result.Name)
+			if len(result.Removed) > 0 {
+				fmt.Fprintf(stdout, "      %d old release(s) removed: %s\n", len(result.Removed), strings.Join(result.Removed, ", "))
+			}
+		}
+		r.logElapsed(stdout, startTime)
+		return releaseErr
+	}
+
+	// fetch: 서버별 하위 디렉토리로 다운로드
+	if script.Fetch != "" {
+		remotePath, localPath, err := parseFetchPath(script.Fetch)
+		if err != nil {
+			return err
+		}
+		client, err := r.getClient(serverName, server)
+		if err != nil {
+			return err
+		}
+		localDir := filepath.Join(localPath, serverName)
+		r.logScript(stdout, "📥 Fetch", fmt.Sprintf("%s → %s", remotePath, localDir))
+		result, fetchErr := client.Download(remotePath, localDir)
+		if fetchErr == nil {
+			fmt.Fprintf(stdout, "      %d file(s) downloaded\n", result.Files)
+		}
+		r.logElapsed(stdout, startTime)
+		return fetchErr
+	}
+
 	// 원격 실행
 	if script.Run != "" {
 		r.logScript(stdout, "▶ Run", script.Run)
@@ -284,7 +540,11 @@ func (r *Runner) runScript(serverName string, server config.Server, script confi
 		if err != nil {
 			return err
 		}
-		err = client.Run(script.Run, stdout, stderr)
+		if script.TTY {
+			err = client.RunTTY(script.Run, stdout, stderr)
+		} else {
+			err = client.Run(script.Run, stdout, stderr)
+		}
 		r.logElapsed(stdout, startTime)
 		return err
 	}
@@ -293,6 +553,7 @@ func (r *Runner) runScript(serverName string, server config.Server, script confi
 }
 
 func (r *Runner) logScript(w io.Writer, prefix, cmd string) {
+	cmd = r.prompter.Redact(cmd)
 	msg := fmt.Sprintf("   %s: %s\n", prefix, truncate(cmd, 60))
 	fmt.Fprint(w, msg)
 	if r.logFile != nil {
@@ -308,36 +569,251 @@ func (r *Runner) logElapsed(w io.Writer, startTime time.Time) {
 	}
 }
 
-func (r *Runner) runLocal(command string, stdout, stderr io.Writer) error {
-	cmd := exec.Command("sh", "-c", command)
+func (r *Runner) runLocal(ctx context.Context, command string, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 	return cmd.Run()
 }
 
 func (r *Runner) getClient(serverName string, server config.Server) (*ssh.Client, error) {
+	return r.connect(serverName, serverName, server, 0)
+}
+
+// pendingDial is a connection being dialed. err is set before done is closed,
+// so callers waiting on the same key get the dial's error instead of redialing.
+type pendingDial struct {
+	done chan struct{}
+	err  error
+}
+
+// connect returns the cached client for key or dials server, through its
+// jump hosts if any. Callers asking for the same key share one dial; name
+// is what the log shows.
+func (r *Runner) connect(key, name string, server config.Server, depth int) (*ssh.Client, error) {
+	reconnecting := false
+	r.mu.Lock()
+	for {
+		// 같은 연결을 맺는 중이면 끝나길 기다렸다가 결과를 공유
+		if pending, ok := r.dialing[key]; ok {
+			r.mu.Unlock()
+			<-pending.done
+			if pending.err != nil {
+				return nil, pending.err
+			}
+			r.mu.Lock()
+			continue
+		}
+		client, ok := r.clients[key]
+		if !ok {
+			break
+		}
+
+		// 오래 쉰 연결이면 Alive가 서버 왕복을 하므로 잠금 없이 확인 (다른 호스트를 막지 않도록)
+		r.mu.Unlock()
+		alive := client.Alive()
+		r.mu.Lock()
+		if alive {
+			r.mu.Unlock()
+			return client, nil
+		}
+		// 끊긴 연결은 버리고 다시 연결 (그 사이 다른 쪽이 바꿨으면 그대로 둠)
+		if r.clients[key] == client {
+			client.Close()
+			delete(r.clients, key)
+			reconnecting = true
+		}
+	}
+	pending := &pendingDial{done: make(chan struct{})}
+	r.dialing[key] = pending
+	r.mu.Unlock()
+
+	if reconnecting {
+		r.log("   🔄 [%s] connection lost, reconnecting...\n", name)
+	}
+	client, err := r.dial(name, server, depth, reconnecting)
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	delete(r.dialing, key)
+	pending.err = err
+	close(pending.done)
+	if err != nil {
+		return nil, err
+	}
+	if r.interrupted {
+		client.Interrupt()
+	}
+	r.clients[key] = client
+	r.opened = append(r.opened, key)
+	return client, nil
+}
+
+// dial opens a new connection to server. At most cap(r.dials) handshakes
+// run at once; jump hosts are resolved before taking a slot.
+func (r *Runner) dial(name string, server config.Server, depth int, reconnecting bool) (*ssh.Client, error) {
+	var via *ssh.Client
+	if server.Jump != "" {
+		var err error
+		via, err = r.jumpClient(server, depth)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	if client, ok := r.clients[serverName]; ok {
-		return client, nil
+	// key 다음에 keys 순서로 시도 (둘 다 없으면 기본 키)
+	var keys []string
+	if server.Key != "" {
+		keys = append(keys, server.Key)
+	}
+	keys = append(keys, server.Keys...)
+
+	opts := ssh.Options{
+		Host:           getHost(server),
+		User:           server.User,
+		Port:           server.Port,
+		Keys:           keys,
+		IdentitiesOnly: server.IdentitiesOnly,
+		Cert:           server.Cert,
+		Auth:           server.Auth,
+		Password:       ssh.SecretSource{Env: server.PasswordEnv, Command: server.PasswordCmd},
+		KnownHosts:     server.KnownHosts,
+		HostKey:        server.HostKey,
+		HostKeyCheck:   server.HostKeyCheck,
+		Transfer:       server.Transfer,
+		Prompter:       r.prompter,
+		Keepalive:      server.Keepalive,
+		KeepaliveMax:   server.KeepaliveMax,
+		Via:            via,
 	}
 
-	keyPath := server.Key
-	if keyPath == "" {
-		keyPath = "~/.ssh/id_rsa"
+	attempts := 1
+	if reconnecting {
+		attempts = server.Reconnect
+		if attempts <= 0 {
+			attempts = defaultReconnect
+		}
 	}
 
-	client, err := ssh.NewClient(getHost(server), server.User, keyPath, server.Port)
+	var client *ssh.Client
+	var err error
+	for attempt := 1; ; attempt++ {
+		r.dials <- struct{}{}
+		client, err = ssh.NewClient(opts)
+		<-r.dials
+		if err == nil || attempt >= attempts {
+			break
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if reconnecting {
+		r.log("   🔄 [%s] reconnected\n", name)
+	}
 
 	client.SetVerbose(r.verbose)
-	r.clients[serverName] = client
 	return client, nil
 }
 
+// jumpClient returns the connection to the last hop of server.Jump; earlier hops
+// are reached recursively. Hop connections are cached so all hosts behind the
+// same bastion share them.
+func (r *Runner) jumpClient(server config.Server, depth int) (*ssh.Client, error) {
+	if depth > 8 {
+		return nil, fmt.Errorf("jump chain too deep (loop in jump configuration?)")
+	}
+
+	var hops []string
+	for _, hop := range strings.Split(server.Jump, ",") {
+		if hop = strings.TrimSpace(hop); hop != "" {
+			hops = append(hops, hop)
+		}
+	}
+	if len(hops) == 0 {
+		return nil, nil
+	}
+
+	last := hops[len(hops)-1]
+	hopServer, err := r.config.JumpServer(last, server)
+	if err != nil {
+		return nil, err
+	}
+	// 마지막 hop은 앞의 hop들을 거쳐 연결 (단일 hop이면 자신의 jump 설정을 따름)
+	if len(hops) > 1 {
+		hopServer.Jump = strings.Join(hops[:len(hops)-1], ",")
+	}
+
+	name := "jump:" + strings.Join(hops, ",")
+	client, err := r.connect(jumpKey(name, hopServer), name, hopServer, depth+1)
+	if err != nil {
+		return nil, fmt.Errorf("jump host %s: %w", last, err)
+	}
+	return client, nil
+}
+
+// jumpKey identifies a hop connection by its chain and the settings it is
+// dialed with: a user@host hop inherits keys and host key settings from the
+// server behind it, so servers with different credentials must not share it.
+func jumpKey(name string, hop config.Server) string {
+	settings := []string{
+		hop.User, getHost(hop), strconv.Itoa(hop.Port), hop.Key, strings.Join(hop.Keys, ","),
+		strconv.FormatBool(hop.IdentitiesOnly), hop.Cert, hop.Auth, hop.PasswordEnv, hop.PasswordCmd,
+		hop.KnownHosts, hop.HostKey, hop.HostKeyCheck, hop.Jump,
+	}
+	return fmt.Sprintf("%s %q", name, settings)
+}
+
+// uploadOptions builds the upload settings of a sync/tar/scp step
+func uploadOptions(script config.Script) (ssh.UploadOptions, error) {
+	preserve, err := ssh.ParsePreserve(script.Preserve)
+	if err != nil {
+		return ssh.UploadOptions{}, err
+	}
+	opts := ssh.UploadOptions{
+		Preserve:  preserve,
+		Exclude:   script.Exclude,
+		Include:   script.Include,
+		MaxDelete: script.MaxDelete,
+		Delta:     script.Delta,
+	}
+
+	switch script.Delete {
+	case "", "false":
+	case "true":
+		opts.Delete = true
+	case "dry-run":
+		opts.Delete = true
+		opts.DryRun = true
+	default:
+		return ssh.UploadOptions{}, fmt.Errorf("invalid delete: %s (expected true, false or dry-run)", script.Delete)
+	}
+	if opts.Delete && script.Sync == "" {
+		return ssh.UploadOptions{}, fmt.Errorf("delete is only supported on sync steps")
+	}
+	if opts.Delta && script.Sync == "" {
+		return ssh.UploadOptions{}, fmt.Errorf("delta is only supported on sync steps")
+	}
+
+	return opts, nil
+}
+
+// logDeleted lists remote files removed by a mirroring sync
+func logDeleted(w io.Writer, result ssh.SyncResult) {
+	if len(result.Deleted) == 0 {
+		return
+	}
+	if result.DryRun {
+		fmt.Fprintf(w, "      %d remote path(s) would be deleted (dry run):\n", len(result.Deleted))
+	} else {
+		fmt.Fprintf(w, "      %d remote path(s) deleted:\n", len(result.Deleted))
+	}
+	for _, path := range result.Deleted {
+		fmt.Fprintf(w, "        - %s\n", path)
+	}
+}
+
 func parseUploadPath(path string) (local, remote string, err error) {
 	parts := strings.SplitN(path, ":", 2)
 	if len(parts) != 2 {
@@ -346,6 +822,15 @@ func parseUploadPath(path string) (local, remote string, err error) {
 	return parts[0], parts[1], nil
 }
 
+// parseFetchPath splits "remote:local" of a fetch step
+func parseFetchPath(path string) (remote, local string, err error) {
+	parts := strings.SplitN(path, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid path format: %s (expected 'remote:local')", path)
+	}
+	return parts[0], parts[1], nil
+}
+
 func truncate(s string, max int) string {
 	s = strings.ReplaceAll(s, "\n", " ")
 	if len(s) > max {
@@ -0,0 +1,445 @@
+// Package sftp is a minimal SFTP version 3 client, enough for gorelay's
+// uploads and downloads over the SSH "sftp" subsystem. It is kept in-tree
+// so gorelay depends on nothing beyond golang.org/x.
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// 패킷당 최대 데이터 크기 (모든 서버가 지원하는 크기)
+const maxDataLen = 32 * 1024
+
+// 파이프라인으로 동시에 보낼 최대 요청 수
+const maxInflight = 64
+
+type response struct {
+	typ  byte
+	data []byte
+}
+
+// Client speaks SFTP over a subsystem's stdin/stdout.
+// Requests may be issued concurrently; replies are matched by request id.
+type Client struct {
+	w io.WriteCloser
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  uint32
+	pending map[uint32]chan response
+	err     error // Set when the reader stops
+
+	extensions map[string]string
+}
+
+// NewClient performs the version handshake and starts reading replies
+func NewClient(r io.Reader, w io.WriteCloser) (*Client, error) {
+	c := &Client{
+		w:          w,
+		pending:    make(map[uint32]chan response),
+		extensions: make(map[string]string),
+	}
+
+	init := packet{0, 0, 0, 0, fxpInit}.uint32(3)
+	if _, err := w.Write(init.finish()); err != nil {
+		return nil, fmt.Errorf("sftp: failed to send init: %w", err)
+	}
+
+	typ, data, err := readPacket(r)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: failed to read version: %w", err)
+	}
+	if typ != fxpVersion {
+		return nil, fmt.Errorf("sftp: unexpected packet type %d during handshake", typ)
+	}
+	rd := &reader{data: data}
+	if version := rd.uint32(); version != 3 {
+		return nil, fmt.Errorf("sftp: unsupported protocol version %d", version)
+	}
+	for len(rd.data) > 0 && rd.err == nil {
+		name := rd.string()
+		c.extensions[name] = rd.string()
+	}
+
+	go c.recv(r)
+	return c, nil
+}
+
+// Close ends the session's stdin, which makes the server exit
+func (c *Client) Close() error {
+	return c.w.Close()
+}
+
+func (c *Client) recv(r io.Reader) {
+	for {
+		typ, data, err := readPacket(r)
+		if err != nil {
+			c.mu.Lock()
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			c.err = err
+			for id, ch := range c.pending {
+				close(ch)
+				delete(c.pending, id)
+			}
+			c.mu.Unlock()
+			return
+		}
+		if len(data) < 4 {
+			continue
+		}
+
+		id := binary.BigEndian.Uint32(data)
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if ok {
+			ch <- response{typ: typ, data: data[4:]}
+		}
+	}
+}
+
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > 1<<24 {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", length)
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return header[4], data, nil
+}
+
+// send writes a request and returns the channel its reply arrives on
+func (c *Client) send(typ byte, build func(packet) packet) (<-chan response, error) {
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, fmt.Errorf("sftp: connection lost: %w", err)
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan response, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	p := build(newPacket(typ, id))
+
+	c.writeMu.Lock()
+	_, err := c.w.Write(p.finish())
+	c.writeMu.Unlock()
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, fmt.Errorf("sftp: failed to send request: %w", err)
+	}
+	return ch, nil
+}
+
+func (c *Client) wait(ch <-chan response) (response, error) {
+	resp, ok := <-ch
+	if !ok {
+		c.mu.Lock()
+		err := c.err
+		c.mu.Unlock()
+		return response{}, fmt.Errorf("sftp: connection lost: %w", err)
+	}
+	return resp, nil
+}
+
+func (c *Client) request(typ byte, build func(packet) packet) (response, error) {
+	ch, err := c.send(typ, build)
+	if err != nil {
+		return response{}, err
+	}
+	return c.wait(ch)
+}
+
+// statusError converts a STATUS reply to an error (nil for OK)
+func statusError(resp response) error {
+	if resp.typ != fxpStatus {
+		return fmt.Errorf("sftp: unexpected packet type %d", resp.typ)
+	}
+	rd := &reader{data: resp.data}
+	code := rd.uint32()
+	msg := rd.string()
+	if rd.err != nil {
+		return rd.err
+	}
+	if code == statusOK {
+		return nil
+	}
+	if code == statusEOF {
+		return io.EOF
+	}
+	return &StatusError{Code: code, Message: msg}
+}
+
+// expectStatus runs a request whose only reply is a STATUS
+func (c *Client) expectStatus(typ byte, build func(packet) packet) error {
+	resp, err := c.request(typ, build)
+	if err != nil {
+		return err
+	}
+	return statusError(resp)
+}
+
+func (c *Client) expectAttrs(typ byte, name string) (*Attrs, error) {
+	resp, err := c.request(typ, func(p packet) packet { return p.string(name) })
+	if err != nil {
+		return nil, err
+	}
+	if resp.typ != fxpAttrs {
+		return nil, pathError(typ, name, statusError(resp))
+	}
+	rd := &reader{data: resp.data}
+	attrs := rd.attrs()
+	return attrs, rd.err
+}
+
+func pathError(op byte, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	ops := map[byte]string{
+		fxpOpen: "open", fxpLstat: "lstat", fxpStat: "stat", fxpSetstat: "setstat",
+		fxpOpendir: "opendir", fxpRemove: "remove", fxpMkdir: "mkdir", fxpRmdir: "rmdir",
+		fxpRename: "rename", fxpReadlink: "readlink", fxpSymlink: "symlink", fxpRealpath: "realpath",
+	}
+	return &os.PathError{Op: ops[op], Path: name, Err: err}
+}
+
+// Stat returns attributes, following symlinks
+func (c *Client) Stat(name string) (*Attrs, error) {
+	return c.expectAttrs(fxpStat, name)
+}
+
+// Lstat returns attributes without following symlinks
+func (c *Client) Lstat(name string) (*Attrs, error) {
+	return c.expectAttrs(fxpLstat, name)
+}
+
+// Mkdir creates a single directory
+func (c *Client) Mkdir(name string, mode os.FileMode) error {
+	err := c.expectStatus(fxpMkdir, func(p packet) packet {
+		return p.string(name).attrs(&Attrs{Flags: attrPermissions, Mode: uint32(mode.Perm())})
+	})
+	return pathError(fxpMkdir, name, err)
+}
+
+// MkdirAll creates a directory and any missing parents
+func (c *Client) MkdirAll(name string, mode os.FileMode) error {
+	if attrs, err := c.Stat(name); err == nil {
+		if attrs.IsDir() {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	parent := path.Dir(name)
+	if parent != name && parent != "." && parent != "/" {
+		if err := c.MkdirAll(parent, mode); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mkdir(name, mode); err != nil {
+		// 다른 요청이 먼저 만들었을 수 있음
+		if attrs, statErr := c.Stat(name); statErr == nil && attrs.IsDir() {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Setstat changes attributes (permissions, times) of a path
+func (c *Client) Setstat(name string, attrs *Attrs) error {
+	err := c.expectStatus(fxpSetstat, func(p packet) packet { return p.string(name).attrs(attrs) })
+	return pathError(fxpSetstat, name, err)
+}
+
+// Chmod changes permission bits
+func (c *Client) Chmod(name string, mode os.FileMode) error {
+	return c.Setstat(name, &Attrs{Flags: attrPermissions, Mode: PosixMode(mode)})
+}
+
+// Chtimes changes access and modification times
+func (c *Client) Chtimes(name string, atime, mtime time.Time) error {
+	return c.Setstat(name, &Attrs{Flags: attrACModTime, Atime: uint32(atime.Unix()), Mtime: uint32(mtime.Unix())})
+}
+
+// Remove deletes a file or symlink
+func (c *Client) Remove(name string) error {
+	err := c.expectStatus(fxpRemove, func(p packet) packet { return p.string(name) })
+	return pathError(fxpRemove, name, err)
+}
+
+// Rmdir deletes an empty directory
+func (c *Client) Rmdir(name string) error {
+	err := c.expectStatus(fxpRmdir, func(p packet) packet { return p.string(name) })
+	return pathError(fxpRmdir, name, err)
+}
+
+// Rename moves oldname to newname, replacing newname when the server supports
+// posix-rename@openssh.com (plain SFTP v3 rename fails if newname exists)
+func (c *Client) Rename(oldname, newname string) error {
+	if _, ok := c.extensions["posix-rename@openssh.com"]; ok {
+		err := c.expectStatus(fxpExtended, func(p packet) packet {
+			return p.string("posix-rename@openssh.com").string(oldname).string(newname)
+		})
+		return pathError(fxpRename, newname, err)
+	}
+	err := c.expectStatus(fxpRename, func(p packet) packet { return p.string(oldname).string(newname) })
+	return pathError(fxpRename, newname, err)
+}
+
+// Symlink creates link pointing to target. OpenSSH expects the arguments
+// in (target, link) order, the reverse of the draft specification.
+func (c *Client) Symlink(target, link string) error {
+	err := c.expectStatus(fxpSymlink, func(p packet) packet { return p.string(target).string(link) })
+	return pathError(fxpSymlink, link, err)
+}
+
+// Readlink returns the target of a symlink
+func (c *Client) Readlink(name string) (string, error) {
+	return c.nameRequest(fxpReadlink, name)
+}
+
+// RealPath canonicalizes a path on the server
+func (c *Client) RealPath(name string) (string, error) {
+	return c.nameRequest(fxpRealpath, name)
+}
+
+func (c *Client) nameRequest(typ byte, name string) (string, error) {
+	resp, err := c.request(typ, func(p packet) packet { return p.string(name) })
+	if err != nil {
+		return "", err
+	}
+	if resp.typ != fxpName {
+		return "", pathError(typ, name, statusError(resp))
+	}
+	rd := &reader{data: resp.data}
+	if rd.uint32() < 1 {
+		return "", pathError(typ, name, errShortPacket)
+	}
+	result := rd.string()
+	return result, rd.err
+}
+
+// DirEntry is one entry returned by ReadDir
+type DirEntry struct {
+	Name  string
+	Attrs *Attrs
+}
+
+// ReadDir lists a directory (without "." and "..")
+func (c *Client) ReadDir(name string) ([]DirEntry, error) {
+	handle, err := c.openHandle(fxpOpendir, name, func(p packet) packet { return p.string(name) })
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeHandle(handle)
+
+	var entries []DirEntry
+	for {
+		resp, err := c.request(fxpReaddir, func(p packet) packet { return p.string(handle) })
+		if err != nil {
+			return nil, err
+		}
+		if resp.typ != fxpName {
+			if err := statusError(resp); err == io.EOF {
+				return entries, nil
+			} else {
+				return nil, pathError(fxpOpendir, name, err)
+			}
+		}
+
+		rd := &reader{data: resp.data}
+		count := rd.uint32()
+		for i := uint32(0); i < count && rd.err == nil; i++ {
+			filename := rd.string()
+			rd.string() // longname
+			attrs := rd.attrs()
+			if filename != "." && filename != ".." {
+				entries = append(entries, DirEntry{Name: filename, Attrs: attrs})
+			}
+		}
+		if rd.err != nil {
+			return nil, rd.err
+		}
+	}
+}
+
+func (c *Client) openHandle(typ byte, name string, build func(packet) packet) (string, error) {
+	resp, err := c.request(typ, build)
+	if err != nil {
+		return "", err
+	}
+	if resp.typ != fxpHandle {
+		return "", pathError(typ, name, statusError(resp))
+	}
+	rd := &reader{data: resp.data}
+	handle := rd.string()
+	return handle, rd.err
+}
+
+func (c *Client) closeHandle(handle string) error {
+	return c.expectStatus(fxpClose, func(p packet) packet { return p.string(handle) })
+}
+
+// Create opens name for writing, truncating or creating it with mode
+func (c *Client) Create(name string, mode os.FileMode) (*File, error) {
+	flags := uint32(flagWrite | flagCreate | flagTrunc)
+	attrs := &Attrs{Flags: attrPermissions, Mode: PosixMode(mode)}
+	handle, err := c.openHandle(fxpOpen, name, func(p packet) packet {
+		return p.string(name).uint32(flags).attrs(attrs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &File{c: c, name: name, handle: handle}, nil
+}
+
+// Open opens name for reading
+func (c *Client) Open(name string) (*File, error) {
+	handle, err := c.openHandle(fxpOpen, name, func(p packet) packet {
+		return p.string(name).uint32(flagRead).attrs(nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &File{c: c, name: name, handle: handle}, nil
+}
+
+// PosixMode converts os.FileMode permission, setuid, setgid and sticky bits
+// to POSIX mode bits
+func PosixMode(mode os.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= 01000
+	}
+	return m
+}
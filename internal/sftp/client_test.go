@@ -0,0 +1,780 @@
+package sftp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is an SFTP v3 server backed by a local directory, just enough
+// to exercise the client over net.Pipe
+type fakeServer struct {
+	root       string
+	extensions []string        // name, value pairs sent with VERSION
+	maxRead    int             // READ replies carry at most this many bytes (0: no limit)
+	hold       int             // The first hold READ/WRITE replies are held back and sent in reverse
+	deny       map[string]bool // Paths answered with permission denied
+
+	mu      sync.Mutex
+	ops     []byte
+	files   map[string]*os.File
+	dirs    map[string][]os.DirEntry
+	nextID  int
+	held    [][]byte
+	maxHeld int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	return &fakeServer{
+		root:       t.TempDir(),
+		extensions: []string{"posix-rename@openssh.com", "1"},
+		deny:       map[string]bool{},
+		files:      map[string]*os.File{},
+		dirs:       map[string][]os.DirEntry{},
+	}
+}
+
+// start connects a client to the server over net.Pipe
+func (s *fakeServer) start(t *testing.T) *Client {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	go s.serve(serverConn)
+
+	c, err := NewClient(clientConn, clientConn)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+		serverConn.Close()
+		s.mu.Lock()
+		for _, f := range s.files {
+			f.Close()
+		}
+		s.mu.Unlock()
+	})
+	return c
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	typ, _, err := readPacket(conn)
+	if err != nil || typ != fxpInit {
+		return
+	}
+	version := packet{0, 0, 0, 0, fxpVersion}.uint32(3)
+	for _, ext := range s.extensions {
+		version = version.string(ext)
+	}
+	if _, err := conn.Write(version.finish()); err != nil {
+		return
+	}
+
+	for {
+		typ, data, err := readPacket(conn)
+		if err != nil {
+			return
+		}
+		rd := &reader{data: data}
+		id := rd.uint32()
+
+		s.mu.Lock()
+		s.ops = append(s.ops, typ)
+		s.mu.Unlock()
+
+		reply := s.handle(typ, id, rd).finish()
+
+		if (typ == fxpRead || typ == fxpWrite) && s.hold > 0 {
+			s.held = append(s.held, reply)
+			s.maxHeld = max(s.maxHeld, len(s.held))
+			if len(s.held) < s.hold {
+				continue
+			}
+			for i := len(s.held) - 1; i >= 0; i-- {
+				if _, err := conn.Write(s.held[i]); err != nil {
+					return
+				}
+			}
+			s.held = nil
+			s.hold = 0
+			continue
+		}
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) path(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+func (s *fakeServer) newHandle() string {
+	s.nextID++
+	return "h" + strconv.Itoa(s.nextID)
+}
+
+func (s *fakeServer) handle(typ byte, id uint32, rd *reader) packet {
+	switch typ {
+	case fxpOpen:
+		name, pflags, attrs := rd.string(), rd.uint32(), rd.attrs()
+		if s.deny[name] {
+			return statusPacket(id, statusPermissionDenied, "denied")
+		}
+		flags := os.O_RDONLY
+		switch {
+		case pflags&flagRead != 0 && pflags&flagWrite != 0:
+			flags = os.O_RDWR
+		case pflags&flagWrite != 0:
+			flags = os.O_WRONLY
+		}
+		for bit, f := range map[uint32]int{flagAppend: os.O_APPEND, flagCreate: os.O_CREATE, flagTrunc: os.O_TRUNC, flagExcl: os.O_EXCL} {
+			if pflags&bit != 0 {
+				flags |= f
+			}
+		}
+		perm := os.FileMode(0644)
+		if attrs.Flags&attrPermissions != 0 {
+			perm = os.FileMode(attrs.Mode & 0777)
+		}
+		f, err := os.OpenFile(s.path(name), flags, perm)
+		if err != nil {
+			return errorPacket(id, err)
+		}
+		h := s.newHandle()
+		s.mu.Lock()
+		s.files[h] = f
+		s.mu.Unlock()
+		return newPacket(fxpHandle, id).string(h)
+
+	case fxpClose:
+		h := rd.string()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if f, ok := s.files[h]; ok {
+			delete(s.files, h)
+			return errorPacket(id, f.Close())
+		}
+		if _, ok := s.dirs[h]; ok {
+			delete(s.dirs, h)
+			return errorPacket(id, nil)
+		}
+		return statusPacket(id, statusFailure, "invalid handle")
+
+	case fxpRead:
+		h, offset, length := rd.string(), rd.uint64(), rd.uint32()
+		if s.maxRead > 0 {
+			length = min(length, uint32(s.maxRead))
+		}
+		buf := make([]byte, length)
+		n, err := s.files[h].ReadAt(buf, int64(offset))
+		if n == 0 && err == io.EOF {
+			return statusPacket(id, statusEOF, "EOF")
+		}
+		if n == 0 && err != nil {
+			return errorPacket(id, err)
+		}
+		return newPacket(fxpData, id).bytes(buf[:n])
+
+	case fxpWrite:
+		h, offset, data := rd.string(), rd.uint64(), rd.bytes()
+		_, err := s.files[h].WriteAt(data, int64(offset))
+		return errorPacket(id, err)
+
+	case fxpStat, fxpLstat:
+		name := rd.string()
+		if s.deny[name] {
+			return statusPacket(id, statusPermissionDenied, "denied")
+		}
+		stat := os.Stat
+		if typ == fxpLstat {
+			stat = os.Lstat
+		}
+		info, err := stat(s.path(name))
+		if err != nil {
+			return errorPacket(id, err)
+		}
+		return newPacket(fxpAttrs, id).attrs(fileAttrs(info))
+
+	case fxpSetstat:
+		name, attrs := rd.string(), rd.attrs()
+		if attrs.Flags&attrPermissions != 0 {
+			if err := os.Chmod(s.path(name), os.FileMode(attrs.Mode&0777)); err != nil {
+				return errorPacket(id, err)
+			}
+		}
+		if attrs.Flags&attrACModTime != 0 {
+			atime, mtime := time.Unix(int64(attrs.Atime), 0), time.Unix(int64(attrs.Mtime), 0)
+			if err := os.Chtimes(s.path(name), atime, mtime); err != nil {
+				return errorPacket(id, err)
+			}
+		}
+		return errorPacket(id, nil)
+
+	case fxpOpendir:
+		name := rd.string()
+		entries, err := os.ReadDir(s.path(name))
+		if err != nil {
+			return errorPacket(id, err)
+		}
+		h := s.newHandle()
+		s.mu.Lock()
+		s.dirs[h] = entries
+		s.mu.Unlock()
+		return newPacket(fxpHandle, id).string(h)
+
+	case fxpReaddir:
+		h := rd.string()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		entries := s.dirs[h]
+		if len(entries) == 0 {
+			return statusPacket(id, statusEOF, "EOF")
+		}
+		// 두 개씩 나눠 보내서 여러 번의 READDIR을 거치게 함
+		batch := entries[:min(2, len(entries))]
+		s.dirs[h] = entries[len(batch):]
+		p := newPacket(fxpName, id).uint32(uint32(len(batch)))
+		for _, e := range batch {
+			info, _ := e.Info()
+			p = p.string(e.Name()).string("").attrs(fileAttrs(info))
+		}
+		return p
+
+	case fxpRemove:
+		name := rd.string()
+		if info, err := os.Lstat(s.path(name)); err == nil && info.IsDir() {
+			return statusPacket(id, statusFailure, "is a directory")
+		}
+		return errorPacket(id, os.Remove(s.path(name)))
+
+	case fxpMkdir:
+		name, attrs := rd.string(), rd.attrs()
+		return errorPacket(id, os.Mkdir(s.path(name), os.FileMode(attrs.Mode&0777)))
+
+	case fxpRmdir:
+		return errorPacket(id, rmdir(s.path(rd.string())))
+
+	case fxpRealpath:
+		name := path.Clean("/" + rd.string())
+		return newPacket(fxpName, id).uint32(1).string(name).string("").attrs(nil)
+
+	case fxpRename:
+		oldname, newname := rd.string(), rd.string()
+		// SFTP v3 rename은 대상이 있으면 실패
+		if _, err := os.Lstat(s.path(newname)); err == nil {
+			return statusPacket(id, statusFailure, "file exists")
+		}
+		return errorPacket(id, os.Rename(s.path(oldname), s.path(newname)))
+
+	case fxpReadlink:
+		target, err := os.Readlink(s.path(rd.string()))
+		if err != nil {
+			return errorPacket(id, err)
+		}
+		return newPacket(fxpName, id).uint32(1).string(target).string("").attrs(nil)
+
+	case fxpSymlink:
+		target, link := rd.string(), rd.string()
+		return errorPacket(id, os.Symlink(target, s.path(link)))
+
+	case fxpExtended:
+		ext := rd.string()
+		if ext == "posix-rename@openssh.com" && len(s.extensions) > 0 {
+			oldname, newname := rd.string(), rd.string()
+			return errorPacket(id, os.Rename(s.path(oldname), s.path(newname)))
+		}
+		return statusPacket(id, statusOpUnsupported, "unsupported")
+	}
+	return statusPacket(id, statusOpUnsupported, "unsupported")
+}
+
+func (s *fakeServer) sawOp(typ byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return bytes.IndexByte(s.ops, typ) >= 0
+}
+
+func rmdir(name string) error {
+	info, err := os.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("not a directory")
+	}
+	return os.Remove(name)
+}
+
+func statusPacket(id, code uint32, msg string) packet {
+	return newPacket(fxpStatus, id).uint32(code).string(msg).string("")
+}
+
+func errorPacket(id uint32, err error) packet {
+	switch {
+	case err == nil:
+		return statusPacket(id, statusOK, "")
+	case errors.Is(err, fs.ErrNotExist):
+		return statusPacket(id, statusNoSuchFile, err.Error())
+	case errors.Is(err, fs.ErrPermission):
+		return statusPacket(id, statusPermissionDenied, err.Error())
+	}
+	return statusPacket(id, statusFailure, err.Error())
+}
+
+func fileAttrs(info os.FileInfo) *Attrs {
+	mode := PosixMode(info.Mode())
+	switch {
+	case info.IsDir():
+		mode |= 0040000
+	case info.Mode()&os.ModeSymlink != 0:
+		mode |= 0120000
+	default:
+		mode |= 0100000
+	}
+	return &Attrs{
+		Flags: attrSize | attrPermissions | attrACModTime,
+		Size:  uint64(info.Size()),
+		Mode:  mode,
+		Atime: uint32(info.ModTime().Unix()),
+		Mtime: uint32(info.ModTime().Unix()),
+	}
+}
+
+// within fails the test when fn does not return in time (a client that does
+// not pipeline deadlocks against a server holding replies back)
+func within(t *testing.T, fn func() error) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out")
+		return nil
+	}
+}
+
+func TestAttrsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs *Attrs
+	}{
+		{"empty", &Attrs{}},
+		{"size", &Attrs{Flags: attrSize, Size: 1<<40 + 7}},
+		{"uidgid", &Attrs{Flags: attrUIDGID, UID: 1000, GID: 100}},
+		{"permissions", &Attrs{Flags: attrPermissions, Mode: 0100755}},
+		{"times", &Attrs{Flags: attrACModTime, Atime: 1700000000, Mtime: 1700000001}},
+		{"all", &Attrs{Flags: attrSize | attrUIDGID | attrPermissions | attrACModTime, Size: 42, UID: 1, GID: 2, Mode: 040700, Atime: 3, Mtime: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := newPacket(fxpAttrs, 7).attrs(tt.attrs).uint32(0xdeadbeef).finish()
+			typ, data, err := readPacket(bytes.NewReader(raw))
+			if err != nil || typ != fxpAttrs {
+				t.Fatalf("readPacket = %d, %v", typ, err)
+			}
+			rd := &reader{data: data}
+			if id := rd.uint32(); id != 7 {
+				t.Fatalf("id = %d, want 7", id)
+			}
+			got := rd.attrs()
+			if trailer := rd.uint32(); rd.err != nil || trailer != 0xdeadbeef {
+				t.Fatalf("attrs consumed the wrong number of bytes (trailer %#x, err %v)", trailer, rd.err)
+			}
+			if *got != *tt.attrs {
+				t.Errorf("attrs = %+v, want %+v", *got, *tt.attrs)
+			}
+		})
+	}
+}
+
+func TestAttrsExtendedSkipped(t *testing.T) {
+	// 클라이언트는 확장 속성을 보내지 않지만 서버가 보내면 건너뛰어야 함
+	p := packet{}.uint32(attrSize | attrExtended).uint64(9).
+		uint32(2).string("a@x").string("1").string("b@x").string("2").
+		uint32(0xdeadbeef)
+	rd := &reader{data: p}
+	a := rd.attrs()
+	if rd.err != nil || a.Size != 9 {
+		t.Fatalf("attrs = %+v, err %v", a, rd.err)
+	}
+	if trailer := rd.uint32(); trailer != 0xdeadbeef {
+		t.Errorf("trailer = %#x, extended pairs not skipped", trailer)
+	}
+}
+
+func TestReaderShortPacket(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		read func(*reader)
+	}{
+		{"uint32", []byte{0, 0, 1}, func(r *reader) { r.uint32() }},
+		{"uint64", []byte{0, 0, 0, 0, 1}, func(r *reader) { r.uint64() }},
+		{"string length", []byte{0, 0, 0, 5, 'a', 'b'}, func(r *reader) { r.string() }},
+		{"attrs size", []byte{0, 0, 0, attrSize, 0, 0}, func(r *reader) { r.attrs() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd := &reader{data: tt.data}
+			tt.read(rd)
+			if rd.err != errShortPacket {
+				t.Errorf("err = %v, want errShortPacket", rd.err)
+			}
+		})
+	}
+}
+
+func TestReadPacketInvalidLength(t *testing.T) {
+	for _, length := range []uint32{0, 1<<24 + 1} {
+		raw := packet{}.uint32(length).byte(fxpStatus)
+		if _, _, err := readPacket(bytes.NewReader(raw)); err == nil {
+			t.Errorf("length %d: expected an error", length)
+		}
+	}
+}
+
+func TestFileMode(t *testing.T) {
+	tests := []struct {
+		mode uint32
+		want os.FileMode
+	}{
+		{0100644, 0644},
+		{0040755, os.ModeDir | 0755},
+		{0120777, os.ModeSymlink | 0777},
+		{0010600, os.ModeNamedPipe | 0600},
+		{0140600, os.ModeSocket | 0600},
+		{0020620, os.ModeDevice | os.ModeCharDevice | 0620},
+		{0060660, os.ModeDevice | 0660},
+		{0104755, os.ModeSetuid | 0755},
+		{0102755, os.ModeSetgid | 0755},
+		{0041777, os.ModeDir | os.ModeSticky | 0777},
+	}
+	for _, tt := range tests {
+		a := &Attrs{Mode: tt.mode}
+		if got := a.FileMode(); got != tt.want {
+			t.Errorf("FileMode(%o) = %v, want %v", tt.mode, got, tt.want)
+		}
+		if got := PosixMode(tt.want) &^ 0170000; got != tt.mode&07777 {
+			t.Errorf("PosixMode(%v) = %o, want %o", tt.want, got, tt.mode&07777)
+		}
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	s := newFakeServer(t)
+	s.deny["secret"] = true
+	c := s.start(t)
+
+	_, err := c.Stat("missing")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat(missing) = %v, want os.ErrNotExist", err)
+	}
+	var pathErr *os.PathError
+	if !errors.As(err, &pathErr) || pathErr.Op != "stat" || pathErr.Path != "missing" {
+		t.Errorf("Stat(missing) = %#v, want a stat PathError", err)
+	}
+
+	if _, err := c.Open("secret"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Open(secret) = %v, want os.ErrPermission", err)
+	}
+
+	if err := c.Mkdir("dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	err = c.Mkdir("dir", 0755)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != statusFailure {
+		t.Errorf("Mkdir(existing) = %v, want a failure status", err)
+	}
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+		t.Errorf("failure status %v must not match os errors", err)
+	}
+
+	if err := statusError(response{typ: fxpStatus, data: packet{}.uint32(statusEOF).string("")}); err != io.EOF {
+		t.Errorf("EOF status = %v, want io.EOF", err)
+	}
+	if err := statusError(response{typ: fxpStatus, data: packet{}.uint32(statusOK).string("")}); err != nil {
+		t.Errorf("OK status = %v, want nil", err)
+	}
+	if err := statusError(response{typ: fxpData}); err == nil {
+		t.Error("unexpected packet type should be an error")
+	}
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	s := newFakeServer(t)
+	s.hold = 4
+	c := s.start(t)
+
+	data := make([]byte, 5*maxDataLen+123)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	f, err := c.Create("data.bin", 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	err = within(t, func() error {
+		var err error
+		n, err = f.ReadFrom(bytes.NewReader(data))
+		return err
+	})
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("ReadFrom = %d, %v", n, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s.maxHeld != 4 {
+		t.Errorf("server saw %d WRITE requests in flight, want 4", s.maxHeld)
+	}
+
+	onDisk, err := os.ReadFile(filepath.Join(s.root, "data.bin"))
+	if err != nil || !bytes.Equal(onDisk, data) {
+		t.Fatalf("uploaded file differs (%d bytes, err %v)", len(onDisk), err)
+	}
+	if info, _ := os.Stat(filepath.Join(s.root, "data.bin")); info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	// 짧은 응답과 역순 응답을 섞어서 WriteTo가 순서대로 이어 붙이는지 확인
+	s.hold = 4
+	s.maxHeld = 0
+	s.maxRead = 1000
+	f, err = c.Open("data.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = within(t, func() error {
+		var err error
+		n, err = f.WriteTo(&buf)
+		return err
+	})
+	if err != nil || n != int64(len(data)) || !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("WriteTo = %d, %v (content equal: %v)", n, err, bytes.Equal(buf.Bytes(), data))
+	}
+	if s.maxHeld != 4 {
+		t.Errorf("server saw %d READ requests in flight, want 4", s.maxHeld)
+	}
+	f.Close()
+
+	// Read는 짧은 응답을 그대로 돌려줌
+	f, err = c.Open("data.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	chunk := make([]byte, maxDataLen)
+	if n, err := f.Read(chunk); err != nil || n != 1000 {
+		t.Errorf("Read = %d, %v, want a short read of 1000", n, err)
+	}
+	rest, err := io.ReadAll(f)
+	if err != nil || !bytes.Equal(rest, data[1000:]) {
+		t.Errorf("ReadAll after short read: %d bytes, %v", len(rest), err)
+	}
+}
+
+func TestWriteToEmptyFile(t *testing.T) {
+	s := newFakeServer(t)
+	c := s.start(t)
+
+	if err := os.WriteFile(filepath.Join(s.root, "empty"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := c.Open("empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	if n, err := f.WriteTo(&buf); err != nil || n != 0 {
+		t.Errorf("WriteTo = %d, %v", n, err)
+	}
+}
+
+func TestRename(t *testing.T) {
+	write := func(s *fakeServer, name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(s.root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("posix-rename", func(t *testing.T) {
+		s := newFakeServer(t)
+		c := s.start(t)
+		write(s, "new", "new")
+		write(s, "old", "old")
+
+		if err := c.Rename("new", "old"); err != nil {
+			t.Fatalf("Rename over an existing file: %v", err)
+		}
+		if got, _ := os.ReadFile(filepath.Join(s.root, "old")); string(got) != "new" {
+			t.Errorf("target = %q, want %q", got, "new")
+		}
+		if !s.sawOp(fxpExtended) || s.sawOp(fxpRename) {
+			t.Errorf("ops = %v, want posix-rename@openssh.com only", s.ops)
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		s := newFakeServer(t)
+		s.extensions = nil
+		c := s.start(t)
+		write(s, "a", "a")
+		write(s, "b", "b")
+
+		if err := c.Rename("a", "c"); err != nil {
+			t.Fatalf("Rename: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(s.root, "c")); err != nil {
+			t.Errorf("renamed file missing: %v", err)
+		}
+		// 확장이 없으면 일반 RENAME이므로 기존 대상을 덮어쓰지 못함
+		var pathErr *os.PathError
+		if err := c.Rename("c", "b"); !errors.As(err, &pathErr) || pathErr.Op != "rename" || pathErr.Path != "b" {
+			t.Errorf("Rename over an existing file = %v, want a rename PathError", err)
+		}
+		if s.sawOp(fxpExtended) || !s.sawOp(fxpRename) {
+			t.Errorf("ops = %v, want plain RENAME only", s.ops)
+		}
+	})
+}
+
+func TestDirectoryOperations(t *testing.T) {
+	s := newFakeServer(t)
+	c := s.start(t)
+
+	if err := c.MkdirAll("a/b/c", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.MkdirAll("a/b/c", 0755); err != nil {
+		t.Errorf("MkdirAll on an existing directory: %v", err)
+	}
+	attrs, err := c.Stat("a/b/c")
+	if err != nil || !attrs.IsDir() {
+		t.Fatalf("Stat(a/b/c) = %+v, %v", attrs, err)
+	}
+
+	for _, name := range []string{"a/one", "a/two", "a/three"} {
+		f, err := c.Create(name, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	if err := c.MkdirAll("a/one/x", 0755); err == nil {
+		t.Error("MkdirAll through a file should fail")
+	}
+
+	entries, err := c.ReadDir("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"b", "one", "three", "two"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir = %v, want %v", names, want)
+	}
+
+	if err := c.Symlink("one", "a/link"); err != nil {
+		t.Fatal(err)
+	}
+	if target, err := c.Readlink("a/link"); err != nil || target != "one" {
+		t.Errorf("Readlink = %q, %v", target, err)
+	}
+	if attrs, err := c.Lstat("a/link"); err != nil || attrs.FileMode()&os.ModeSymlink == 0 {
+		t.Errorf("Lstat(link) = %+v, %v, want a symlink", attrs, err)
+	}
+	if attrs, err := c.Stat("a/link"); err != nil || attrs.Size != uint64(len("a/one")) {
+		t.Errorf("Stat(link) = %+v, %v, want the target's attributes", attrs, err)
+	}
+
+	mtime := time.Unix(1600000000, 0)
+	if err := c.Chmod("a/one", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Chtimes("a/one", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if attrs, err := c.Stat("a/one"); err != nil || attrs.FileMode() != 0600 || !attrs.ModTime().Equal(mtime) {
+		t.Errorf("after Chmod/Chtimes: %+v, %v", attrs, err)
+	}
+
+	if p, err := c.RealPath("a/b/../one"); err != nil || p != "/a/one" {
+		t.Errorf("RealPath = %q, %v", p, err)
+	}
+
+	if err := c.Remove("a/b"); err == nil {
+		t.Error("Remove on a directory should fail")
+	}
+	if err := c.Rmdir("a/b/c"); err != nil {
+		t.Error(err)
+	}
+	if err := c.Remove("a/link"); err != nil {
+		t.Error(err)
+	}
+	if _, err := c.Lstat("a/link"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Lstat after Remove = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestConnectionLost(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	go func() {
+		readPacket(serverConn)
+		serverConn.Write(packet{0, 0, 0, 0, fxpVersion}.uint32(3).finish())
+		readPacket(serverConn) // 첫 요청을 받고 끊음
+		serverConn.Close()
+	}()
+
+	c, err := NewClient(clientConn, clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	_, err = c.Stat("x")
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Stat after disconnect = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := c.Stat("x"); err == nil {
+		t.Error("requests after disconnect should fail")
+	}
+}
+
+func TestHandshakeRejectsOtherVersions(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() {
+		readPacket(serverConn)
+		serverConn.Write(packet{0, 0, 0, 0, fxpVersion}.uint32(6).finish())
+	}()
+
+	if _, err := NewClient(clientConn, clientConn); err == nil {
+		t.Error("expected an error for protocol version 6")
+	}
+}
@@ -0,0 +1,226 @@
+package sftp
+
+import (
+	"io"
+)
+
+// File is an open remote file handle
+type File struct {
+	c      *Client
+	name   string
+	handle string
+	offset int64
+}
+
+// Name returns the remote path the file was opened with
+func (f *File) Name() string {
+	return f.name
+}
+
+// Close releases the remote handle. For written files, errors from the
+// server's final flush are reported here.
+func (f *File) Close() error {
+	return pathError(fxpClose, f.name, f.c.closeHandle(f.handle))
+}
+
+// Write writes p at the current offset
+func (f *File) Write(p []byte) (int, error) {
+	n, err := f.ReadFrom(&chunkReader{data: p})
+	return int(n), err
+}
+
+// ReadFrom copies r to the file, keeping several WRITE requests in flight
+// so throughput is not limited by round-trip latency.
+func (f *File) ReadFrom(r io.Reader) (int64, error) {
+	type inflight struct {
+		ch <-chan response
+		n  int
+	}
+
+	var (
+		queue   []inflight
+		written int64
+		buf     = make([]byte, maxDataLen)
+	)
+
+	drain := func(limit int) error {
+		for len(queue) > limit {
+			resp, err := f.c.wait(queue[0].ch)
+			if err == nil {
+				err = statusError(resp)
+			}
+			if err != nil {
+				return pathError(fxpOpen, f.name, err)
+			}
+			written += int64(queue[0].n)
+			queue = queue[1:]
+		}
+		return nil
+	}
+
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			offset := f.offset
+			data := buf[:n]
+			ch, err := f.c.send(fxpWrite, func(p packet) packet {
+				return p.string(f.handle).uint64(uint64(offset)).bytes(data)
+			})
+			if err != nil {
+				drain(0)
+				return written, err
+			}
+			f.offset += int64(n)
+			queue = append(queue, inflight{ch: ch, n: n})
+			if err := drain(maxInflight - 1); err != nil {
+				drain(0)
+				return written, err
+			}
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return written, drain(0)
+		}
+		if readErr != nil {
+			drain(0)
+			return written, readErr
+		}
+	}
+}
+
+// Read reads up to len(p) bytes from the current offset
+func (f *File) Read(p []byte) (int, error) {
+	if len(p) > maxDataLen {
+		p = p[:maxDataLen]
+	}
+	data, err := f.readAt(f.offset, len(p))
+	n := copy(p, data)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *File) readAt(offset int64, length int) ([]byte, error) {
+	resp, err := f.c.request(fxpRead, func(p packet) packet {
+		return p.string(f.handle).uint64(uint64(offset)).uint32(uint32(length))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.typ != fxpData {
+		if err := statusError(resp); err == io.EOF {
+			return nil, io.EOF
+		} else {
+			return nil, pathError(fxpOpen, f.name, err)
+		}
+	}
+	rd := &reader{data: resp.data}
+	data := rd.bytes()
+	return data, rd.err
+}
+
+// WriteTo copies the file to w, keeping several READ requests in flight
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	type inflight struct {
+		ch     <-chan response
+		offset int64
+	}
+
+	var (
+		queue   []inflight
+		written int64
+		eof     bool
+		start   = f.offset
+	)
+
+	issue := func() error {
+		offset := f.offset
+		ch, err := f.c.send(fxpRead, func(p packet) packet {
+			return p.string(f.handle).uint64(uint64(offset)).uint32(maxDataLen)
+		})
+		if err != nil {
+			return err
+		}
+		queue = append(queue, inflight{ch: ch, offset: offset})
+		f.offset += maxDataLen
+		return nil
+	}
+
+	// 남은 응답은 모두 받아야 채널이 정리됨
+	discard := func() {
+		for _, q := range queue {
+			f.c.wait(q.ch)
+		}
+	}
+
+	for !eof || len(queue) > 0 {
+		for !eof && len(queue) < maxInflight {
+			if err := issue(); err != nil {
+				discard()
+				return written, err
+			}
+		}
+
+		head := queue[0]
+		queue = queue[1:]
+		resp, err := f.c.wait(head.ch)
+		if err != nil {
+			discard()
+			return written, err
+		}
+
+		if resp.typ != fxpData {
+			err := statusError(resp)
+			if err == io.EOF {
+				eof = true
+				continue
+			}
+			discard()
+			return written, pathError(fxpOpen, f.name, err)
+		}
+
+		rd := &reader{data: resp.data}
+		data := rd.bytes()
+		if rd.err != nil {
+			discard()
+			return written, rd.err
+		}
+		if head.offset != start+written {
+			// 순서가 어긋남 (앞의 응답이 EOF였는데 뒤 요청이 데이터를 받음)
+			discard()
+			return written, io.ErrUnexpectedEOF
+		}
+		n, err := w.Write(data)
+		written += int64(n)
+		if err != nil {
+			discard()
+			return written, err
+		}
+
+		if len(data) < maxDataLen {
+			// 짧은 응답: 나머지를 다시 요청해야 하므로 파이프라인을 재정렬
+			discard()
+			queue = nil
+			f.offset = head.offset + int64(len(data))
+			if len(data) == 0 {
+				eof = true
+			}
+		}
+	}
+
+	f.offset = start + written
+	return written, nil
+}
+
+// chunkReader adapts a byte slice to io.Reader without copying it
+type chunkReader struct {
+	data []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
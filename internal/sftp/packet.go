@@ -0,0 +1,270 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Packet types (SFTP version 3, draft-ietf-secsh-filexfer-02)
+const (
+	fxpInit          = 1
+	fxpVersion       = 2
+	fxpOpen          = 3
+	fxpClose         = 4
+	fxpRead          = 5
+	fxpWrite         = 6
+	fxpLstat         = 7
+	fxpFstat         = 8
+	fxpSetstat       = 9
+	fxpOpendir       = 11
+	fxpReaddir       = 12
+	fxpRemove        = 13
+	fxpMkdir         = 14
+	fxpRmdir         = 15
+	fxpRealpath      = 16
+	fxpStat          = 17
+	fxpRename        = 18
+	fxpReadlink      = 19
+	fxpSymlink       = 20
+	fxpStatus        = 101
+	fxpHandle        = 102
+	fxpData          = 103
+	fxpName          = 104
+	fxpAttrs         = 105
+	fxpExtended      = 200
+	fxpExtendedReply = 201
+)
+
+// Open flags
+const (
+	flagRead   = 0x01
+	flagWrite  = 0x02
+	flagAppend = 0x04
+	flagCreate = 0x08
+	flagTrunc  = 0x10
+	flagExcl   = 0x20
+)
+
+// Attribute flags
+const (
+	attrSize        = 0x01
+	attrUIDGID      = 0x02
+	attrPermissions = 0x04
+	attrACModTime   = 0x08
+	attrExtended    = 0x80000000
+)
+
+// Status codes
+const (
+	statusOK               = 0
+	statusEOF              = 1
+	statusNoSuchFile       = 2
+	statusPermissionDenied = 3
+	statusFailure          = 4
+	statusBadMessage       = 5
+	statusNoConnection     = 6
+	statusConnectionLost   = 7
+	statusOpUnsupported    = 8
+)
+
+// StatusError is an SSH_FXP_STATUS reply other than OK
+type StatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("sftp: %s (code %d)", e.Message, e.Code)
+	}
+	return fmt.Sprintf("sftp: status code %d", e.Code)
+}
+
+// Is maps status codes to the matching os errors, so errors.Is(err, os.ErrNotExist) works
+func (e *StatusError) Is(target error) bool {
+	switch e.Code {
+	case statusNoSuchFile:
+		return target == os.ErrNotExist
+	case statusPermissionDenied:
+		return target == os.ErrPermission
+	}
+	return false
+}
+
+var errShortPacket = errors.New("sftp: short packet")
+
+// Attrs are the file attributes exchanged with the server
+type Attrs struct {
+	Flags uint32
+	Size  uint64
+	UID   uint32
+	GID   uint32
+	Mode  uint32 // Permission and file type bits (S_IFMT)
+	Atime uint32
+	Mtime uint32
+}
+
+// FileMode converts the POSIX mode bits to os.FileMode
+func (a *Attrs) FileMode() os.FileMode {
+	mode := os.FileMode(a.Mode & 0777)
+	switch a.Mode & 0170000 {
+	case 0040000:
+		mode |= os.ModeDir
+	case 0120000:
+		mode |= os.ModeSymlink
+	case 0010000:
+		mode |= os.ModeNamedPipe
+	case 0140000:
+		mode |= os.ModeSocket
+	case 0020000:
+		mode |= os.ModeDevice | os.ModeCharDevice
+	case 0060000:
+		mode |= os.ModeDevice
+	}
+	if a.Mode&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if a.Mode&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if a.Mode&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// ModTime returns the modification time
+func (a *Attrs) ModTime() time.Time {
+	return time.Unix(int64(a.Mtime), 0)
+}
+
+// IsDir reports whether the attributes describe a directory
+func (a *Attrs) IsDir() bool {
+	return a.Mode&0170000 == 0040000
+}
+
+// packet builds an outgoing message
+type packet []byte
+
+func newPacket(typ byte, id uint32) packet {
+	p := packet{0, 0, 0, 0, typ}
+	return p.uint32(id)
+}
+
+func (p packet) byte(v byte) packet {
+	return append(p, v)
+}
+
+func (p packet) uint32(v uint32) packet {
+	return binary.BigEndian.AppendUint32(p, v)
+}
+
+func (p packet) uint64(v uint64) packet {
+	return binary.BigEndian.AppendUint64(p, v)
+}
+
+func (p packet) string(s string) packet {
+	p = p.uint32(uint32(len(s)))
+	return append(p, s...)
+}
+
+func (p packet) bytes(b []byte) packet {
+	p = p.uint32(uint32(len(b)))
+	return append(p, b...)
+}
+
+func (p packet) attrs(a *Attrs) packet {
+	if a == nil {
+		return p.uint32(0)
+	}
+	p = p.uint32(a.Flags &^ attrExtended)
+	if a.Flags&attrSize != 0 {
+		p = p.uint64(a.Size)
+	}
+	if a.Flags&attrUIDGID != 0 {
+		p = p.uint32(a.UID).uint32(a.GID)
+	}
+	if a.Flags&attrPermissions != 0 {
+		p = p.uint32(a.Mode)
+	}
+	if a.Flags&attrACModTime != 0 {
+		p = p.uint32(a.Atime).uint32(a.Mtime)
+	}
+	return p
+}
+
+// finish fills in the length prefix
+func (p packet) finish() []byte {
+	binary.BigEndian.PutUint32(p, uint32(len(p)-4))
+	return p
+}
+
+// reader decodes an incoming message
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) uint32() uint32 {
+	if r.err != nil || len(r.data) < 4 {
+		r.err = errShortPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *reader) uint64() uint64 {
+	if r.err != nil || len(r.data) < 8 {
+		r.err = errShortPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return v
+}
+
+func (r *reader) bytes() []byte {
+	n := r.uint32()
+	if r.err != nil || uint32(len(r.data)) < n {
+		r.err = errShortPacket
+		return nil
+	}
+	v := r.data[:n]
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *reader) string() string {
+	return string(r.bytes())
+}
+
+func (r *reader) attrs() *Attrs {
+	a := &Attrs{Flags: r.uint32()}
+	if a.Flags&attrSize != 0 {
+		a.Size = r.uint64()
+	}
+	if a.Flags&attrUIDGID != 0 {
+		a.UID = r.uint32()
+		a.GID = r.uint32()
+	}
+	if a.Flags&attrPermissions != 0 {
+		a.Mode = r.uint32()
+	}
+	if a.Flags&attrACModTime != 0 {
+		a.Atime = r.uint32()
+		a.Mtime = r.uint32()
+	}
+	if a.Flags&attrExtended != 0 {
+		count := r.uint32()
+		for i := uint32(0); i < count && r.err == nil; i++ {
+			r.string()
+			r.string()
+		}
+	}
+	return a
+}
@@ -0,0 +1,257 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Authentication methods
+const (
+	AuthPublicKey           = "publickey"
+	AuthPassword            = "password"
+	AuthKeyboardInteractive = "keyboard-interactive"
+)
+
+// 키를 지정하지 않았을 때 시도하는 기본 키 (OpenSSH와 동일한 순서)
+var defaultKeys = []string{
+	"~/.ssh/id_rsa",
+	"~/.ssh/id_ecdsa",
+	"~/.ssh/id_ed25519",
+}
+
+// keyring holds the signers offered during public key authentication
+type keyring struct {
+	signers []ssh.Signer
+	agent   net.Conn
+	skipped []string // keys that could not be loaded, with reasons
+}
+
+// authMethods builds the methods for opts.Auth. The keyring is only
+// returned for public key authentication (nil otherwise).
+func authMethods(opts Options) ([]ssh.AuthMethod, *keyring, error) {
+	password := func() (string, error) {
+		secret, err := opts.Prompter.Password(opts.User, opts.Host, opts.Port, opts.Password)
+		return string(secret), err
+	}
+	// 모든 질문에 같은 비밀번호로 응답 (PAM 기반 장비가 대부분 "Password:" 하나만 물음)
+	challenge := func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		if len(questions) == 0 {
+			return answers, nil
+		}
+		secret, err := password()
+		if err != nil {
+			return nil, err
+		}
+		for i := range answers {
+			answers[i] = secret
+		}
+		return answers, nil
+	}
+
+	switch opts.Auth {
+	case "", AuthPublicKey:
+		// ssh-agent → 키 파일 순서로 인증 시도
+		keys := loadKeyring(opts)
+		if len(keys.signers) == 0 {
+			keys.Close()
+			return nil, nil, fmt.Errorf("no SSH identities available (%s)", keys.describe())
+		}
+		return []ssh.AuthMethod{keys.authMethod()}, keys, nil
+	case AuthPassword:
+		// password를 막아둔 서버는 keyboard-interactive로 같은 비밀번호를 받음
+		return []ssh.AuthMethod{
+			ssh.PasswordCallback(password),
+			ssh.KeyboardInteractive(challenge),
+		}, nil, nil
+	case AuthKeyboardInteractive:
+		return []ssh.AuthMethod{ssh.KeyboardInteractive(challenge)}, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("invalid auth: %s (expected publickey, password or keyboard-interactive)", opts.Auth)
+	}
+}
+
+// loadKeyring collects ssh-agent identities first, then the configured key files.
+// Keys that cannot be loaded are skipped so the remaining ones can still be tried.
+func loadKeyring(opts Options) *keyring {
+	kr := &keyring{}
+
+	keys := opts.Keys
+	if len(keys) == 0 {
+		keys = defaultKeys
+	}
+
+	// 인증서 후보: cert 설정, 그다음 각 키의 <key>-cert.pub
+	var certFiles []string
+	if opts.Cert != "" {
+		certFiles = append(certFiles, opts.Cert)
+	}
+	for _, keyPath := range keys {
+		certFiles = append(certFiles, keyPath+"-cert.pub")
+	}
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		conn, err := net.Dial("unix", sock)
+		if err == nil {
+			signers, err := agent.NewClient(conn).Signers()
+			if err == nil {
+				kr.agent = conn
+				for _, signer := range signers {
+					// IdentitiesOnly: 설정된 키 파일과 같은 에이전트 키만 사용
+					if opts.IdentitiesOnly && !matchesKeyFile(signer, keys) {
+						continue
+					}
+					kr.add(signer, certFiles)
+				}
+			} else {
+				conn.Close()
+				kr.skipped = append(kr.skipped, fmt.Sprintf("ssh-agent: %v", err))
+			}
+		} else {
+			kr.skipped = append(kr.skipped, fmt.Sprintf("ssh-agent: %v", err))
+		}
+	}
+
+	for _, keyPath := range keys {
+		// ssh-agent에 이미 있는 키는 암호를 묻지 않도록 건너뜀
+		if kr.hasPublicKey(keyPath) {
+			continue
+		}
+
+		data, err := os.ReadFile(expandPath(keyPath))
+		if err != nil {
+			// 기본 키는 없을 수 있으므로 조용히 넘어감
+			if len(opts.Keys) > 0 || !os.IsNotExist(err) {
+				kr.skipped = append(kr.skipped, fmt.Sprintf("%s: %v", keyPath, err))
+			}
+			continue
+		}
+
+		signer, err := parsePrivateKey(keyPath, data, opts.Prompter)
+		if err != nil {
+			kr.skipped = append(kr.skipped, fmt.Sprintf("%s: %v", keyPath, err))
+			continue
+		}
+		kr.add(signer, certFiles)
+	}
+
+	return kr
+}
+
+// add appends signer, preceded by a certificate signer when one of certFiles
+// certifies its public key (OpenSSH also offers the certificate first).
+func (kr *keyring) add(signer ssh.Signer, certFiles []string) {
+	for _, certPath := range certFiles {
+		data, err := os.ReadFile(expandPath(certPath))
+		if err != nil {
+			continue
+		}
+		pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			kr.skipped = append(kr.skipped, fmt.Sprintf("%s: %v", certPath, err))
+			continue
+		}
+		cert, ok := pub.(*ssh.Certificate)
+		if !ok || !bytes.Equal(cert.Key.Marshal(), signer.PublicKey().Marshal()) {
+			continue
+		}
+
+		now := uint64(time.Now().Unix())
+		if cert.ValidBefore != ssh.CertTimeInfinity && now >= cert.ValidBefore {
+			kr.skipped = append(kr.skipped, fmt.Sprintf("%s: certificate expired at %s", certPath,
+				time.Unix(int64(cert.ValidBefore), 0).Format("2006-01-02 15:04:05")))
+			continue
+		}
+
+		certSigner, err := ssh.NewCertSigner(cert, signer)
+		if err != nil {
+			kr.skipped = append(kr.skipped, fmt.Sprintf("%s: %v", certPath, err))
+			continue
+		}
+		kr.signers = append(kr.signers, certSigner)
+		break
+	}
+	kr.signers = append(kr.signers, signer)
+}
+
+// hasPublicKey reports whether the key's .pub file matches an identity already loaded
+func (kr *keyring) hasPublicKey(keyPath string) bool {
+	for _, signer := range kr.signers {
+		if matchesKeyFile(signer, []string{keyPath}) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesKeyFile reports whether signer's public key equals one of the keys' .pub files
+func matchesKeyFile(signer ssh.Signer, keyPaths []string) bool {
+	for _, keyPath := range keyPaths {
+		data, err := os.ReadFile(expandPath(keyPath) + ".pub")
+		if err != nil {
+			continue
+		}
+		pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(signer.PublicKey().Marshal(), pub.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrivateKey parses a key file, asking for the passphrase if it is encrypted
+func parsePrivateKey(keyPath string, data []byte, prompter *Prompter) (ssh.Signer, error) {
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return signer, err
+	}
+
+	passphrase, err := prompter.Passphrase(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err = ssh.ParsePrivateKeyWithPassphrase(data, passphrase)
+	if err != nil {
+		// 잘못된 암호는 캐시에 남기지 않음
+		prompter.Forget(keyPath)
+		return nil, fmt.Errorf("failed to decrypt key: %w", err)
+	}
+	return signer, nil
+}
+
+// authMethod offers every signer in order under a single publickey method,
+// since the SSH client tries each method type only once.
+func (kr *keyring) authMethod() ssh.AuthMethod {
+	return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		return kr.signers, nil
+	})
+}
+
+// describe explains which identities were tried, for authentication errors
+func (kr *keyring) describe() string {
+	var parts []string
+	parts = append(parts, fmt.Sprintf("%d identities offered", len(kr.signers)))
+	if len(kr.skipped) > 0 {
+		parts = append(parts, "skipped: "+strings.Join(kr.skipped, "; "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (kr *keyring) Close() {
+	if kr != nil && kr.agent != nil {
+		kr.agent.Close()
+	}
+}
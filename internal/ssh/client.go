@@ -4,15 +4,22 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/yejune/gorelay/internal/ignore"
+	"github.com/yejune/gorelay/internal/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -21,44 +28,128 @@ type Client struct {
 	host    string
 	config  *ssh.ClientConfig
 	verbose bool
+	closed  chan struct{} // Closed when the connection ends
+	done    chan struct{} // Closed by Close to stop keepalive
+	once    sync.Once
+	used    atomic.Int64 // Last session or answered keepalive (UnixNano)
+
+	mu          sync.Mutex
+	sessions    map[*ssh.Session]struct{} // Running sessions (for Interrupt)
+	tempFiles   map[string]struct{}       // Remote temp files (for Cleanup)
+	interrupted bool
+
+	transfer   string // Transfer backend requested in Options
+	transferMu sync.Mutex
+	xfer       transport // Opened on first upload
 }
 
-func NewClient(host, user, keyPath string, port int) (*Client, error) {
+// Options describes how to connect to a server
+type Options struct {
+	Host           string
+	User           string
+	Port           int
+	Keys           []string      // SSH key paths, tried after ssh-agent identities
+	IdentitiesOnly bool          // Only use ssh-agent identities matching Keys
+	Cert           string        // User certificate path (default: <key>-cert.pub if present)
+	Auth           string        // publickey (default), password, keyboard-interactive
+	Password       SecretSource  // Password source for password/keyboard-interactive auth
+	Keepalive      time.Duration // Keepalive interval (0: disabled)
+	KeepaliveMax   int           // Unanswered keepalives before the connection is dropped (default: 3)
+	KnownHosts     string        // Extra known_hosts file
+	HostKey        string        // Pinned host key fingerprint (SHA256:...)
+	HostKeyCheck   string        // strict (default), accept-new, off
+	Transfer       string        // File transfer backend: auto (default), sftp, scp
+	Prompter       *Prompter     // Asks for key passphrases (shared across clients to cache answers)
+	Via            *Client       // Jump host to connect through (nil: direct)
+}
+
+func NewClient(opts Options) (*Client, error) {
+	port := opts.Port
 	if port == 0 {
 		port = 22
 	}
 
-	// SSH 키 읽기
-	key, err := os.ReadFile(expandPath(keyPath))
+	if err := validTransfer(opts.Transfer); err != nil {
+		return nil, err
+	}
+
+	hostKeyCallback, err := hostKeyCallback(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+		return nil, err
+	}
+
+	if opts.Prompter == nil {
+		opts.Prompter = NewPrompter()
 	}
+	opts.Port = port
 
-	signer, err := ssh.ParsePrivateKey(key)
+	auth, keys, err := authMethods(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse SSH key: %w", err)
+		return nil, err
 	}
+	defer keys.Close()
 
+	addr := fmt.Sprintf("%s:%d", opts.Host, port)
 	config := &ssh.ClientConfig{
-		User: user,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
+		User:              opts.User,
+		Auth:              auth,
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: hostKeyAlgorithms(opts, addr),
+		Timeout:           30 * time.Second,
 	}
 
-	addr := fmt.Sprintf("%s:%d", host, port)
-	conn, err := ssh.Dial("tcp", addr, config)
+	conn, err := dial(addr, config, opts.Via)
 	if err != nil {
+		// 거부된 비밀번호는 캐시에 남기지 않음 (재연결 때 다시 물어봄)
+		if opts.Auth != "" && opts.Auth != AuthPublicKey && strings.Contains(err.Error(), "unable to authenticate") {
+			opts.Prompter.ForgetPassword(opts.User, opts.Host, port)
+		}
+		if keys != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w (%s)", addr, err, keys.describe())
+		}
 		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
 	}
 
-	return &Client{
-		conn:   conn,
-		host:   host,
-		config: config,
-	}, nil
+	client := &Client{
+		conn:      conn,
+		host:      opts.Host,
+		config:    config,
+		closed:    make(chan struct{}),
+		done:      make(chan struct{}),
+		sessions:  make(map[*ssh.Session]struct{}),
+		tempFiles: make(map[string]struct{}),
+		transfer:  opts.Transfer,
+	}
+
+	client.touch()
+	go func() {
+		conn.Wait()
+		close(client.closed)
+	}()
+	if opts.Keepalive > 0 {
+		go client.keepalive(opts.Keepalive, opts.KeepaliveMax)
+	}
+
+	return client, nil
+}
+
+// dial connects directly or tunnels through the jump host's connection
+func dial(addr string, config *ssh.ClientConfig, via *Client) (*ssh.Client, error) {
+	if via == nil {
+		return ssh.Dial("tcp", addr, config)
+	}
+
+	tunnel, err := via.conn.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("jump host %s: %w", via.host, err)
+	}
+
+	conn, chans, reqs, err := ssh.NewClientConn(tunnel, addr, config)
+	if err != nil {
+		tunnel.Close()
+		return nil, err
+	}
+	return ssh.NewClient(conn, chans, reqs), nil
 }
 
 func (c *Client) SetVerbose(v bool) {
@@ -66,6 +157,8 @@ func (c *Client) SetVerbose(v bool) {
 }
 
 func (c *Client) Close() error {
+	c.once.Do(func() { close(c.done) })
+	c.closeTransport()
 	if c.conn != nil {
 		return c.conn.Close()
 	}
@@ -73,11 +166,11 @@ func (c *Client) Close() error {
 }
 
 func (c *Client) Run(command string, stdout, stderr io.Writer) error {
-	session, err := c.conn.NewSession()
+	session, err := c.newSession()
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
-	defer session.Close()
+	defer c.closeSession(session)
 
 	session.Stdout = stdout
 	session.Stderr = stderr
@@ -85,64 +178,81 @@ func (c *Client) Run(command string, stdout, stderr io.Writer) error {
 	return session.Run(command)
 }
 
-// UploadSync uploads file/directory with checksum comparison (only changed files)
-func (c *Client) UploadSync(localPath, remotePath string) (int, error) {
+// ExitStatus returns the exit code of a remote command that failed with one
+func ExitStatus(err error) (int, bool) {
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), true
+	}
+	return 0, false
+}
+
+// UploadSync uploads file/directory with checksum comparison (only changed files).
+// With opts.Delete, remote files missing locally are removed (directories only).
+func (c *Client) UploadSync(localPath, remotePath string, opts UploadOptions) (SyncResult, error) {
 	stat, err := os.Stat(localPath)
 	if err != nil {
-		return 0, fmt.Errorf("failed to stat local path: %w", err)
+		return SyncResult{}, fmt.Errorf("failed to stat local path: %w", err)
 	}
 
 	if stat.IsDir() {
-		return c.uploadDirSync(localPath, remotePath)
+		return c.uploadDirSync(localPath, remotePath, opts)
 	}
-	return c.uploadFileSync(localPath, remotePath)
+	uploaded, err := c.uploadFileSync(localPath, remotePath, opts)
+	return SyncResult{Uploaded: uploaded}, err
 }
 
 // UploadTar uploads file/directory as tar.gz (atomic)
-func (c *Client) UploadTar(localPath, remotePath string) error {
+func (c *Client) UploadTar(localPath, remotePath string, opts UploadOptions) error {
 	stat, err := os.Stat(localPath)
 	if err != nil {
 		return fmt.Errorf("failed to stat local path: %w", err)
 	}
 
 	if stat.IsDir() {
-		return c.uploadDirTar(localPath, remotePath)
+		return c.uploadDirTar(localPath, remotePath, opts)
 	}
-	return c.uploadFileTar(localPath, remotePath)
+	return c.uploadFileTar(localPath, remotePath, opts)
 }
 
-// UploadSCP uploads file/directory via SCP (no checksum comparison)
-func (c *Client) UploadSCP(localPath, remotePath string) (int, error) {
+// UploadSCP uploads file/directory without checksum comparison (over SFTP or scp, see Options.Transfer)
+func (c *Client) UploadSCP(localPath, remotePath string, opts UploadOptions) (int, error) {
 	stat, err := os.Stat(localPath)
 	if err != nil {
 		return 0, fmt.Errorf("failed to stat local path: %w", err)
 	}
 
 	if stat.IsDir() {
-		return c.uploadDirSCP(localPath, remotePath)
+		return c.uploadDirSCP(localPath, remotePath, opts)
 	}
-	return c.uploadFileSCP(localPath, remotePath)
+	return c.uploadFileSCP(localPath, remotePath, opts)
 }
 
 // uploadFileSync uploads a single file with checksum verification
-func (c *Client) uploadFileSync(localPath, remotePath string) (int, error) {
+func (c *Client) uploadFileSync(localPath, remotePath string, opts UploadOptions) (int, error) {
 	// 로컬 파일 체크섬 계산
-	localChecksum, err := c.getLocalChecksum(localPath)
+	localChecksum, err := localChecksum(localPath)
 	if err != nil {
 		return 0, err
 	}
 
-	// 원격 파일 체크섬 확인
+	// 원격 파일 체크섬 확인 (모드 보존 시 권한도 비교)
 	remoteChecksum, err := c.getRemoteChecksum(remotePath)
-	if err == nil && remoteChecksum == localChecksum {
+	if err == nil && remoteChecksum == localChecksum && c.remoteModeMatches(localPath, remotePath, opts.Preserve) {
 		if c.verbose {
 			fmt.Printf("      Skip (unchanged): %s\n", filepath.Base(localPath))
 		}
 		return 0, nil // 변경 없음
 	}
 
-	// 변경된 파일 업로드
-	if err := c.scpFile(localPath, remotePath); err != nil {
+	// 변경된 파일 업로드 (델타 모드면 원격에 있는 큰 파일은 바뀐 블록만)
+	send := c.sendFile
+	if info, statErr := os.Stat(localPath); opts.Delta && err == nil && statErr == nil && info.Size() >= deltaMinSize {
+		send = func(localPath, remotePath string, preserve Preserve) error {
+			return c.deltaOrFull(localPath, remotePath, localChecksum, preserve)
+		}
+	}
+	if err := send(localPath, remotePath, opts.Preserve); err != nil {
 		return 0, err
 	}
 
@@ -162,215 +272,254 @@ func (c *Client) uploadFileSync(localPath, remotePath string) (int, error) {
 	return 1, nil
 }
 
-// uploadDirSync uploads a directory with checksum comparison (only changed files)
-func (c *Client) uploadDirSync(localDir, remoteDir string) (int, error) {
-	// 원격 디렉토리 생성
-	if err := c.Run(fmt.Sprintf("mkdir -p %s", remoteDir), io.Discard, io.Discard); err != nil {
-		return 0, fmt.Errorf("failed to create remote directory: %w", err)
+// remoteModeMatches compares a single file's permission bits with the remote copy
+func (c *Client) remoteModeMatches(localPath, remotePath string, preserve Preserve) bool {
+	if !preserve.Mode {
+		return true
 	}
+	remoteMode, found := c.getRemoteMode(remotePath)
+	return modeMatches(localPath, remoteMode, found, preserve)
+}
 
-	// 원격 파일 체크섬 목록 가져오기
-	remoteChecksums, err := c.getRemoteDirChecksums(remoteDir)
+// uploadFileTar uploads a single file as tar.gz (atomic)
+func (c *Client) uploadFileTar(localPath, remotePath string, opts UploadOptions) error {
+	stat, err := os.Stat(localPath)
 	if err != nil {
-		if c.verbose {
-			fmt.Printf("      No existing files on remote (new directory)\n")
-		}
-		remoteChecksums = make(map[string]string)
+		return fmt.Errorf("failed to stat local file: %w", err)
 	}
 
-	uploaded := 0
-	skipped := 0
-
-	err = filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// 상대 경로 계산
-		relPath, _ := filepath.Rel(localDir, path)
-		remotePath := filepath.Join(remoteDir, relPath)
-
-		if info.IsDir() {
-			// 원격 디렉토리 생성
-			return c.Run(fmt.Sprintf("mkdir -p %s", remotePath), io.Discard, io.Discard)
-		}
-
-		// 로컬 파일 체크섬 계산
-		localChecksum, err := c.getLocalChecksum(path)
-		if err != nil {
-			return err
-		}
-
-		// 원격 체크섬과 비교
-		if remoteChecksum, ok := remoteChecksums[relPath]; ok && remoteChecksum == localChecksum {
-			skipped++
-			if c.verbose {
-				fmt.Printf("      Skip (unchanged): %s\n", relPath)
-			}
-			return nil
-		}
+	// 디렉토리로 지정하면 로컬 파일 이름으로 저장
+	if strings.HasSuffix(remotePath, "/") {
+		remotePath += filepath.Base(localPath)
+	}
 
-		// 변경된 파일만 업로드
-		if c.verbose {
-			fmt.Printf("      Upload: %s\n", relPath)
+	err = c.uploadTarStream(remotePath, filepath.Base(localPath), func(tarWriter *tar.Writer) error {
+		header := &tar.Header{
+			Name:    filepath.Base(localPath),
+			Size:    stat.Size(),
+			Mode:    int64(sftp.PosixMode(opts.Preserve.attrs(stat).Mode)),
+			ModTime: stat.ModTime(),
 		}
-		if err := c.scpFile(path, remotePath); err != nil {
-			return err
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write tar header: %w", err)
 		}
-		uploaded++
-		return nil
+		return copyFile(tarWriter, localPath)
 	})
+	if err != nil {
+		return err
+	}
 
 	if c.verbose {
-		fmt.Printf("      Uploaded: %d, Skipped: %d\n", uploaded, skipped)
+		fmt.Printf("      ✓ Extracted to %s\n", remotePath)
 	}
 
-	return uploaded, err
+	return nil
 }
 
-// uploadFileTar uploads a single file as tar.gz (atomic)
-func (c *Client) uploadFileTar(localPath, remotePath string) error {
-	// 로컬 파일 읽기
-	fileContent, err := os.ReadFile(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to read local file: %w", err)
-	}
-
-	stat, err := os.Stat(localPath)
+// uploadDirTar uploads a directory as tar.gz (atomic)
+func (c *Client) uploadDirTar(localDir, remoteDir string, opts UploadOptions) error {
+	matcher, err := ignore.New(localDir, opts.Exclude, opts.Include)
 	if err != nil {
-		return fmt.Errorf("failed to stat local file: %w", err)
+		return err
 	}
 
-	// tar.gz 생성 (메모리)
-	var tarBuffer bytes.Buffer
-	gzWriter := gzip.NewWriter(&tarBuffer)
-	tarWriter := tar.NewWriter(gzWriter)
+	err = c.uploadTarStream(remoteDir, "", func(tarWriter *tar.Writer) error {
+		return filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
 
-	header := &tar.Header{
-		Name:    filepath.Base(localPath),
-		Size:    stat.Size(),
-		Mode:    0644,
-		ModTime: stat.ModTime(),
-	}
-	if err := tarWriter.WriteHeader(header); err != nil {
-		return fmt.Errorf("failed to write tar header: %w", err)
-	}
-	if _, err := tarWriter.Write(fileContent); err != nil {
-		return fmt.Errorf("failed to write tar content: %w", err)
-	}
+			relPath, _ := filepath.Rel(localDir, path)
+			if relPath == "." {
+				return nil
+			}
+			if skip, err := c.skipExcluded(matcher, relPath, info); skip {
+				return err
+			}
 
-	tarWriter.Close()
-	gzWriter.Close()
+			// 심볼릭 링크는 대상 경로를 그대로 기록
+			var link string
+			if info.Mode()&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(path); err != nil {
+					return err
+				}
+			}
 
-	tarContent := tarBuffer.Bytes()
-	tarHash := sha256.Sum256(tarContent)
-	tarHashStr := hex.EncodeToString(tarHash[:])
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(relPath)
 
-	if c.verbose {
-		fmt.Printf("      Tar size: %d bytes\n", len(tarContent))
-		fmt.Printf("      Tar SHA256: %s\n", tarHashStr)
-	}
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return err
+			}
 
-	// 원격에 임시 파일로 업로드
-	remoteTar := fmt.Sprintf("/tmp/gorelay-%s.tar.gz", tarHashStr[:8])
-	if err := c.scpBytes(tarContent, remoteTar); err != nil {
-		return fmt.Errorf("failed to upload tar: %w", err)
-	}
+			if info.Mode().IsRegular() {
+				return copyFile(tarWriter, path)
+			}
 
-	// 원격에서 압축 해제 (원자적 교체)
-	remoteDir := filepath.Dir(remotePath)
-	extractCmd := fmt.Sprintf("mkdir -p %s && tar -xzf %s -C %s && rm -f %s", remoteDir, remoteTar, remoteDir, remoteTar)
-	var stderr bytes.Buffer
-	if err := c.Run(extractCmd, io.Discard, &stderr); err != nil {
-		return fmt.Errorf("failed to extract tar: %w (stderr: %s)", err, stderr.String())
+			return nil
+		})
+	})
+	if err != nil {
+		return err
 	}
 
 	if c.verbose {
-		fmt.Printf("      ✓ Extracted to %s\n", remotePath)
+		fmt.Printf("      ✓ Extracted to %s\n", remoteDir)
 	}
 
 	return nil
 }
 
-// uploadDirTar uploads a directory as tar.gz (atomic)
-func (c *Client) uploadDirTar(localDir, remoteDir string) error {
-	// tar.gz 생성 (메모리)
-	var tarBuffer bytes.Buffer
-	gzWriter := gzip.NewWriter(&tarBuffer)
-	tarWriter := tar.NewWriter(gzWriter)
-
-	err := filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		relPath, _ := filepath.Rel(localDir, path)
-		if relPath == "." {
-			return nil
-		}
-
-		header, err := tar.FileInfoHeader(info, "")
-		if err != nil {
-			return err
+// uploadTarStream streams a tar.gz written by build to a temp file beside
+// remotePath and installs it atomically (see tarInstallScript). The archive
+// is never held in memory: it flows through a pipe while its size and
+// SHA256 are computed on the way. member is the archive entry of a
+// single-file upload, or "" when remotePath is a directory.
+func (c *Client) uploadTarStream(remotePath, member string, build func(*tar.Writer) error) error {
+	t, err := c.transport()
+	if err != nil {
+		return err
+	}
+
+	// 대상과 같은 파일시스템에 두어야 rename이 원자적 (/tmp 이름 충돌도 없음)
+	id := randomID()
+	remotePath = path.Clean(filepath.ToSlash(remotePath))
+	parent := path.Dir(remotePath)
+	remoteTar := path.Join(parent, ".gorelay-"+id+".tar.gz")
+	if err := c.checkStagingDir(remotePath, member == ""); err != nil {
+		return err
+	}
+	c.trackTemp(remoteTar)
+
+	pipeReader, pipeWriter := io.Pipe()
+	hash := sha256.New()
+	counter := &countingWriter{}
+	built := make(chan error, 1)
+
+	go func() {
+		gzWriter := gzip.NewWriter(io.MultiWriter(pipeWriter, hash, counter))
+		tarWriter := tar.NewWriter(gzWriter)
+		err := build(tarWriter)
+		if err == nil {
+			err = tarWriter.Close()
 		}
-		header.Name = relPath
-
-		if err := tarWriter.WriteHeader(header); err != nil {
-			return err
+		if err == nil {
+			err = gzWriter.Close()
 		}
+		pipeWriter.CloseWithError(err)
+		built <- err
+	}()
 
-		if !info.IsDir() {
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer file.Close()
-			if _, err := io.Copy(tarWriter, file); err != nil {
-				return err
-			}
-		}
-
-		return nil
-	})
-	if err != nil {
+	// 원격에 임시 파일로 업로드
+	uploadErr := t.writeFile(remoteTar, pipeReader, -1, fileAttrs{Mode: 0600})
+	// 업로드가 먼저 실패하면 생성 쪽이 막히지 않도록 파이프를 닫음
+	pipeReader.CloseWithError(uploadErr)
+	if err := <-built; err != nil {
 		return fmt.Errorf("failed to create tar: %w", err)
 	}
+	if uploadErr != nil {
+		return fmt.Errorf("failed to upload tar: %w", uploadErr)
+	}
 
-	tarWriter.Close()
-	gzWriter.Close()
-
-	tarContent := tarBuffer.Bytes()
-	tarHash := sha256.Sum256(tarContent)
-	tarHashStr := hex.EncodeToString(tarHash[:])
-
+	sum := hex.EncodeToString(hash.Sum(nil))
 	if c.verbose {
-		fmt.Printf("      Tar size: %d bytes\n", len(tarContent))
-		fmt.Printf("      Tar SHA256: %s\n", tarHashStr)
+		fmt.Printf("      Tar size: %d bytes\n", counter.n)
+		fmt.Printf("      Tar SHA256: %s\n", sum)
 	}
 
-	// 원격에 임시 파일로 업로드
-	remoteTar := fmt.Sprintf("/tmp/gorelay-%s.tar.gz", tarHashStr[:8])
-	if err := c.scpBytes(tarContent, remoteTar); err != nil {
-		return fmt.Errorf("failed to upload tar: %w", err)
+	// 원격에서 체크섬 확인 후 스테이징에 풀고 교체
+	var stdout, stderr bytes.Buffer
+	if err := c.Run(tarInstallScript(remotePath, remoteTar, member, sum, id), &stdout, &stderr); err != nil {
+		return fmt.Errorf("failed to extract tar: %w (stderr: %s)", err, stderr.String())
+	}
+	c.untrackTemp(remoteTar)
+	if strings.TrimSpace(stdout.String()) == "renamed" {
+		fmt.Printf("      ⚠ %s was briefly missing while it was swapped (mv -T --exchange is not available)\n", remotePath)
 	}
 
-	// 원격에서 압축 해제
-	extractCmd := fmt.Sprintf("mkdir -p %s && tar -xzf %s -C %s && rm -f %s", remoteDir, remoteTar, remoteDir, remoteTar)
+	return nil
+}
+
+// checkStagingDir creates the target's parent directory and makes sure the
+// archive and the staging directory can be written there
+func (c *Client) checkStagingDir(target string, dir bool) error {
 	var stderr bytes.Buffer
-	if err := c.Run(extractCmd, io.Discard, &stderr); err != nil {
-		return fmt.Errorf("failed to extract tar: %w (stderr: %s)", err, stderr.String())
+	if err := c.Run(stagingCheckScript(target, dir), io.Discard, &stderr); err != nil {
+		return fmt.Errorf("failed to prepare %s: %w (stderr: %s)", path.Dir(target), err, strings.TrimSpace(stderr.String()))
 	}
+	return nil
+}
 
-	if c.verbose {
-		fmt.Printf("      ✓ Extracted to %s\n", remoteDir)
-	}
+// stagingCheckScript fails with a clear message when the directory the
+// archive is uploaded to, or (for a directory target behind a symlink) the
+// one tarInstallScript stages in, is not writable
+func stagingCheckScript(target string, dir bool) string {
+	lines := []string{
+		fmt.Sprintf("d=%s", shellQuote(target)),
+		`w() { [ -w "$1" ] || { echo "$1 is not writable: tar stages uploads next to the target and needs write access to its parent directory" >&2; exit 1; }; }`,
+		`mkdir -p "$(dirname "$d")" || exit 1`,
+		`w "$(dirname "$d")"`,
+	}
+	if dir {
+		// 링크면 tarInstallScript는 실제 디렉토리 옆에서 교체
+		lines = append(lines, `if [ -L "$d" ] && [ -d "$d" ]; then w "$(dirname "$(cd "$d" && pwd -P)")"; fi`)
+	}
+	return strings.Join(lines, "\n")
+}
 
-	return nil
+// tarInstallScript checks the uploaded archive's SHA256, extracts it into a
+// staging directory next to the target and swaps it in with renames, so a
+// failed or interrupted install leaves the old target in place.
+//
+// A directory target is rebuilt from its current contents plus the archive
+// (files not in the archive are kept, as with a plain extract). The copy
+// needs room for a second tree, and files written into the target between
+// the copy and the swap are lost with the old tree. The swap is a single
+// atomic exchange where mv supports -T --exchange (GNU coreutils 9.5+);
+// otherwise it takes two renames, between which the target briefly does not
+// exist (undone on failure), and the script prints "renamed". A symlinked
+// target directory is replaced behind the link. A single file is renamed
+// into place atomically. checkStagingDir has already made sure the staging
+// location is writable.
+func tarInstallScript(target, archive, member, sum, id string) string {
+	lines := []string{
+		"set -e",
+		remoteHashCmd,
+		fmt.Sprintf("d=%s; t=%s", shellQuote(target), shellQuote(archive)),
+		fmt.Sprintf(`[ "$($h < "$t" | cut -d' ' -f1)" = %s ] || { echo "archive checksum mismatch" >&2; rm -f "$t"; exit 1; }`, sum),
+	}
+
+	if member != "" {
+		return strings.Join(append(lines,
+			fmt.Sprintf(`s="$(dirname "$d")/.gorelay-%s"; m=%s`, id, shellQuote(member)),
+			`trap 'rm -rf "$s" "$t"' EXIT; trap 'exit 1' INT TERM HUP`,
+			`mkdir "$s"`,
+			`tar -xzf "$t" -C "$s"`,
+			`mv -f "$s/$m" "$d"`,
+		), "\n")
+	}
+
+	return strings.Join(append(lines,
+		// 링크면 가리키는 실제 디렉토리를 교체
+		`if [ -L "$d" ] && [ -d "$d" ]; then d=$(cd "$d" && pwd -P); fi`,
+		fmt.Sprintf(`s="$(dirname "$d")/.$(basename "$d").gorelay-%s"; o="$s.old"`, id),
+		// 중간에 실패하거나 중단되면 옛 디렉토리를 되돌리고 임시 파일 정리
+		`cleanup() { if [ ! -e "$d" ] && [ -d "$o" ]; then mv "$o" "$d"; fi; rm -rf "$s" "$o" "$t"; }`,
+		`trap cleanup EXIT; trap 'exit 1' INT TERM HUP`,
+		`mkdir "$s"`,
+		`if [ -d "$d" ]; then cp -RPp "$d/." "$s/"; fi`,
+		`tar -xzf "$t" -C "$s"`,
+		// --exchange가 없으면 두 번 rename (그 사이 잠깐 $d가 없음)
+		`if [ ! -d "$d" ]; then mv "$s" "$d"; elif mv -T --exchange "$s" "$d" 2>/dev/null; then :; else mv "$d" "$o"; mv "$s" "$d"; echo renamed; fi`,
+	), "\n")
 }
 
-// uploadFileSCP uploads a single file via SCP (no checksum)
-func (c *Client) uploadFileSCP(localPath, remotePath string) (int, error) {
-	if err := c.scpFile(localPath, remotePath); err != nil {
+// uploadFileSCP uploads a single file (no checksum)
+func (c *Client) uploadFileSCP(localPath, remotePath string, opts UploadOptions) (int, error) {
+	if err := c.sendFile(localPath, remotePath, opts.Preserve); err != nil {
 		return 0, err
 	}
 	if c.verbose {
@@ -379,16 +528,21 @@ func (c *Client) uploadFileSCP(localPath, remotePath string) (int, error) {
 	return 1, nil
 }
 
-// uploadDirSCP uploads all files in a directory via SCP (no checksum)
-func (c *Client) uploadDirSCP(localDir, remoteDir string) (int, error) {
+// uploadDirSCP uploads all files in a directory (no checksum)
+func (c *Client) uploadDirSCP(localDir, remoteDir string, opts UploadOptions) (int, error) {
+	matcher, err := ignore.New(localDir, opts.Exclude, opts.Include)
+	if err != nil {
+		return 0, err
+	}
+
 	// 원격 디렉토리 생성
-	if err := c.Run(fmt.Sprintf("mkdir -p %s", remoteDir), io.Discard, io.Discard); err != nil {
-		return 0, fmt.Errorf("failed to create remote directory: %w", err)
+	if err := c.mkdirAll(remoteDir); err != nil {
+		return 0, err
 	}
 
 	uploaded := 0
 
-	err := filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -397,16 +551,28 @@ func (c *Client) uploadDirSCP(localDir, remoteDir string) (int, error) {
 		relPath, _ := filepath.Rel(localDir, path)
 		remotePath := filepath.Join(remoteDir, relPath)
 
+		if skip, err := c.skipExcluded(matcher, relPath, info); skip {
+			return err
+		}
+
 		if info.IsDir() {
 			// 원격 디렉토리 생성
-			return c.Run(fmt.Sprintf("mkdir -p %s", remotePath), io.Discard, io.Discard)
+			return c.mkdirAll(remotePath)
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			sent, err := c.uploadSymlink(path, remotePath, relPath, opts.Preserve)
+			if sent {
+				uploaded++
+			}
+			return err
 		}
 
 		// 파일 업로드 (체크섬 비교 없음)
 		if c.verbose {
 			fmt.Printf("      Upload: %s\n", relPath)
 		}
-		if err := c.scpFile(path, remotePath); err != nil {
+		if err := c.sendFile(path, remotePath, opts.Preserve); err != nil {
 			return err
 		}
 		uploaded++
@@ -420,120 +586,73 @@ func (c *Client) uploadDirSCP(localDir, remoteDir string) (int, error) {
 	return uploaded, err
 }
 
-// scpFile transfers a single file via SCP
-func (c *Client) scpFile(localPath, remotePath string) error {
-	fileContent, err := os.ReadFile(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to read local file: %w", err)
-	}
-
-	stat, err := os.Stat(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to stat local file: %w", err)
-	}
-
-	// 원격 디렉토리 생성
-	remoteDir := filepath.Dir(remotePath)
-	c.Run(fmt.Sprintf("mkdir -p %s", remoteDir), io.Discard, io.Discard)
-
-	session, err := c.conn.NewSession()
-	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
-	}
-	defer session.Close()
-
-	stdinPipe, err := session.StdinPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdin pipe: %w", err)
-	}
-
-	var scpStderr bytes.Buffer
-	session.Stderr = &scpStderr
-
-	// SCP 명령 시작 (-t: sink mode)
-	if err := session.Start(fmt.Sprintf("/usr/bin/scp -t %s", remotePath)); err != nil {
-		return fmt.Errorf("failed to start scp: %w", err)
+// skipExcluded reports whether a walked path is excluded by the matcher.
+// For directories it also returns filepath.SkipDir so Walk does not descend.
+func (c *Client) skipExcluded(matcher *ignore.Matcher, relPath string, info os.FileInfo) (bool, error) {
+	if relPath == "." || !matcher.Excluded(relPath, info.IsDir()) {
+		return false, nil
 	}
-
-	// SCP 프로토콜: 파일 헤더 전송 (C<mode> <size> <filename>)
-	header := fmt.Sprintf("C0644 %d %s\n", stat.Size(), filepath.Base(remotePath))
-	if _, err := stdinPipe.Write([]byte(header)); err != nil {
-		return fmt.Errorf("failed to write scp header: %w", err)
+	if c.verbose {
+		fmt.Printf("      Skip (excluded): %s\n", relPath)
 	}
-
-	// 파일 내용 전송
-	if _, err := stdinPipe.Write(fileContent); err != nil {
-		return fmt.Errorf("failed to write file content: %w", err)
+	if info.IsDir() {
+		return true, filepath.SkipDir
 	}
-
-	// 종료 바이트 (0x00)
-	if _, err := stdinPipe.Write([]byte{0}); err != nil {
-		return fmt.Errorf("failed to write terminator: %w", err)
-	}
-
-	stdinPipe.Close()
-
-	if err := session.Wait(); err != nil {
-		return fmt.Errorf("scp failed: %w (stderr: %s)", err, scpStderr.String())
-	}
-
-	return nil
+	return true, nil
 }
 
-// scpBytes transfers bytes content via SCP
-func (c *Client) scpBytes(content []byte, remotePath string) error {
-	session, err := c.conn.NewSession()
-	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+// uploadSymlink recreates a symlink found while walking a directory, or
+// uploads what it points to when links are not preserved.
+// Links to directories are skipped in that case (Walk does not follow them).
+func (c *Client) uploadSymlink(localPath, remotePath, relPath string, preserve Preserve) (bool, error) {
+	if preserve.Links {
+		changed, err := c.sendSymlink(localPath, remotePath)
+		if err != nil {
+			return false, err
+		}
+		if c.verbose {
+			if changed {
+				fmt.Printf("      Link: %s\n", relPath)
+			} else {
+				fmt.Printf("      Skip (unchanged): %s\n", relPath)
+			}
+		}
+		return changed, nil
 	}
-	defer session.Close()
 
-	stdinPipe, err := session.StdinPipe()
+	info, err := os.Stat(localPath)
 	if err != nil {
-		return fmt.Errorf("failed to get stdin pipe: %w", err)
-	}
-
-	var scpStderr bytes.Buffer
-	session.Stderr = &scpStderr
-
-	if err := session.Start(fmt.Sprintf("/usr/bin/scp -t %s", remotePath)); err != nil {
-		return fmt.Errorf("failed to start scp: %w", err)
+		return false, err
 	}
-
-	header := fmt.Sprintf("C0644 %d %s\n", len(content), filepath.Base(remotePath))
-	if _, err := stdinPipe.Write([]byte(header)); err != nil {
-		return fmt.Errorf("failed to write scp header: %w", err)
-	}
-
-	if _, err := stdinPipe.Write(content); err != nil {
-		return fmt.Errorf("failed to write content: %w", err)
+	if info.IsDir() {
+		if c.verbose {
+			fmt.Printf("      Skip (symlink to directory): %s\n", relPath)
+		}
+		return false, nil
 	}
 
-	if _, err := stdinPipe.Write([]byte{0}); err != nil {
-		return fmt.Errorf("failed to write terminator: %w", err)
+	if c.verbose {
+		fmt.Printf("      Upload: %s\n", relPath)
 	}
-
-	stdinPipe.Close()
-
-	if err := session.Wait(); err != nil {
-		return fmt.Errorf("scp failed: %w (stderr: %s)", err, scpStderr.String())
+	if err := c.sendFile(localPath, remotePath, preserve); err != nil {
+		return false, err
 	}
-
-	return nil
+	return true, nil
 }
 
 func (c *Client) getRemoteChecksum(remotePath string) (string, error) {
-	session, err := c.conn.NewSession()
+	session, err := c.newSession()
 	if err != nil {
 		return "", err
 	}
-	defer session.Close()
+	defer c.closeSession(session)
 
 	var stdout, stderr bytes.Buffer
 	session.Stdout = &stdout
 	session.Stderr = &stderr
 
-	cmd := fmt.Sprintf("sha256sum %s 2>/dev/null || shasum -a 256 %s 2>/dev/null", remotePath, remotePath)
+	// 표준 입력으로 읽어 파일 이름이 출력에 섞이지 않게 함
+	cmd := fmt.Sprintf("%s\n$h < %s", remoteHashCmd, shellQuote(remotePath))
 	if err := session.Run(cmd); err != nil {
 		return "", fmt.Errorf("checksum command failed: %w (stderr: %s)", err, stderr.String())
 	}
@@ -547,45 +666,42 @@ func (c *Client) getRemoteChecksum(remotePath string) (string, error) {
 	return parts[0], nil
 }
 
-func (c *Client) getRemoteDirChecksums(remoteDir string) (map[string]string, error) {
-	session, err := c.conn.NewSession()
-	if err != nil {
-		return nil, err
+// localChecksum returns the SHA256 of a local file, read as a stream
+func localChecksum(path string) (string, error) {
+	hash := sha256.New()
+	if err := copyFile(hash, path); err != nil {
+		return "", err
 	}
-	defer session.Close()
-
-	var stdout, stderr bytes.Buffer
-	session.Stdout = &stdout
-	session.Stderr = &stderr
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
 
-	// 모든 파일의 체크섬 가져오기
-	cmd := fmt.Sprintf("find %s -type f -exec sha256sum {} \\; 2>/dev/null || find %s -type f -exec shasum -a 256 {} \\; 2>/dev/null", remoteDir, remoteDir)
-	if err := session.Run(cmd); err != nil {
-		return nil, err
+// copyFile streams a local file into w
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
 
-	checksums := make(map[string]string)
-	for _, line := range strings.Split(stdout.String(), "\n") {
-		parts := strings.Fields(line)
-		if len(parts) >= 2 {
-			hash := parts[0]
-			path := parts[1]
-			// 상대 경로로 변환
-			relPath := strings.TrimPrefix(path, remoteDir+"/")
-			checksums[relPath] = hash
-		}
-	}
+	_, err = io.Copy(w, file)
+	return err
+}
 
-	return checksums, nil
+// countingWriter counts bytes written through it
+type countingWriter struct {
+	n int64
 }
 
-func (c *Client) getLocalChecksum(path string) (string, error) {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
-	}
-	hash := sha256.Sum256(content)
-	return hex.EncodeToString(hash[:]), nil
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// randomID returns a short random hex string for temp file names
+func randomID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
 
 func expandPath(path string) string {
@@ -0,0 +1,165 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func requireTar(t *testing.T) {
+	t.Helper()
+	requireShell(t)
+	if _, err := exec.LookPath("tar"); err != nil {
+		t.Skip("tar not installed")
+	}
+}
+
+func TestTarInstallScriptUpdatesDirectory(t *testing.T) {
+	requireTar(t)
+	dir := t.TempDir()
+	target := filepath.Join(dir, "app")
+	os.MkdirAll(filepath.Join(target, "sub"), 0755)
+	os.WriteFile(filepath.Join(target, "kept"), []byte("kept"), 0644)
+	os.WriteFile(filepath.Join(target, "sub", "changed"), []byte("old"), 0644)
+
+	archive := filepath.Join(dir, ".gorelay-1.tar.gz")
+	sum := writeTestTar(t, archive, map[string]string{"./sub/changed": "new", "./added": "added"})
+	out, stderr, err := runShell(t, dir, tarInstallScript(target, archive, "", sum, "1"))
+	if err != nil {
+		t.Fatalf("install: %v (stderr: %s)", err, stderr)
+	}
+	// mv --exchange가 없으면 rename 두 번으로 교체했다고 알림
+	probe := t.TempDir()
+	os.Mkdir(filepath.Join(probe, "a"), 0755)
+	os.Mkdir(filepath.Join(probe, "b"), 0755)
+	exchange := exec.Command("mv", "-T", "--exchange", filepath.Join(probe, "a"), filepath.Join(probe, "b")).Run() == nil
+	if want := map[bool]string{true: "", false: "renamed"}[exchange]; strings.TrimSpace(out) != want {
+		t.Errorf("output = %q, want %q (mv --exchange supported: %v)", out, want, exchange)
+	}
+
+	for name, want := range map[string]string{"kept": "kept", "sub/changed": "new", "added": "added"} {
+		if got, _ := os.ReadFile(filepath.Join(target, name)); string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("staging files left behind: %v", entries)
+	}
+}
+
+func TestTarInstallScriptReplacesBehindSymlink(t *testing.T) {
+	requireTar(t)
+	dir := t.TempDir()
+	real := filepath.Join(dir, "releases", "v1")
+	os.MkdirAll(real, 0755)
+	os.WriteFile(filepath.Join(real, "kept"), []byte("kept"), 0644)
+	link := filepath.Join(dir, "current")
+	os.Symlink("releases/v1", link)
+
+	archive := filepath.Join(dir, ".gorelay-1.tar.gz")
+	sum := writeTestTar(t, archive, map[string]string{"./added": "added"})
+	if _, stderr, err := runShell(t, dir, tarInstallScript(link, archive, "", sum, "1")); err != nil {
+		t.Fatalf("install: %v (stderr: %s)", err, stderr)
+	}
+
+	if target, err := os.Readlink(link); err != nil || target != "releases/v1" {
+		t.Errorf("link = %q, %v; want it untouched", target, err)
+	}
+	for _, name := range []string{"kept", "added"} {
+		if _, err := os.Stat(filepath.Join(real, name)); err != nil {
+			t.Errorf("%s missing from the linked directory: %v", name, err)
+		}
+	}
+}
+
+func TestTarInstallScriptKeepsOldTreeOnFailure(t *testing.T) {
+	requireTar(t)
+	dir := t.TempDir()
+	target := filepath.Join(dir, "app")
+	os.MkdirAll(target, 0755)
+	os.WriteFile(filepath.Join(target, "old"), []byte("old"), 0644)
+
+	// 체크섬은 맞지만 tar로 풀 수 없는 아카이브
+	archive := filepath.Join(dir, ".gorelay-1.tar.gz")
+	os.WriteFile(archive, []byte("not a tarball"), 0600)
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte("not a tarball")))
+	if _, _, err := runShell(t, dir, tarInstallScript(target, archive, "", sum, "1")); err == nil {
+		t.Fatal("expected the install to fail")
+	}
+
+	if got, _ := os.ReadFile(filepath.Join(target, "old")); string(got) != "old" {
+		t.Errorf("old tree changed: old = %q", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("staging files left behind: %v", entries)
+	}
+}
+
+func TestStagingCheckScript(t *testing.T) {
+	requireShell(t)
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to any directory")
+	}
+	dir := t.TempDir()
+	locked := filepath.Join(dir, "locked")
+	os.MkdirAll(filepath.Join(locked, "app"), 0755)
+	// 쓸 수 있는 곳의 링크가 잠긴 디렉토리 안을 가리킴
+	os.Symlink(filepath.Join(locked, "app"), filepath.Join(dir, "link"))
+	os.Chmod(locked, 0555)
+	defer os.Chmod(locked, 0755)
+
+	tests := []struct {
+		name   string
+		target string
+		dir    bool
+		ok     bool
+	}{
+		{"writable parent", filepath.Join(dir, "app"), true, true},
+		{"missing parent is created", filepath.Join(dir, "new", "app"), true, true},
+		{"unwritable parent", filepath.Join(locked, "app"), true, false},
+		{"link into unwritable parent", filepath.Join(dir, "link"), true, false},
+		{"file replaces the link itself", filepath.Join(dir, "link"), false, true},
+	}
+	for _, tt := range tests {
+		_, stderr, err := runShell(t, dir, stagingCheckScript(tt.target, tt.dir))
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v (stderr: %s)", tt.name, err, stderr)
+		}
+		if !tt.ok && (err == nil || !strings.Contains(stderr, "is not writable")) {
+			t.Errorf("%s: expected a clear error, got %v (stderr: %s)", tt.name, err, stderr)
+		}
+	}
+}
+
+func TestTarInstallScriptFile(t *testing.T) {
+	requireTar(t)
+	dir := t.TempDir()
+	target := filepath.Join(dir, "server-new")
+	// 기존 링크는 따라가지 않고 교체
+	outside := filepath.Join(t.TempDir(), "outside")
+	os.WriteFile(outside, []byte("outside"), 0644)
+	os.Symlink(outside, target)
+
+	archive := filepath.Join(dir, ".gorelay-1.tar.gz")
+	sum := writeTestTar(t, archive, map[string]string{"server": "binary"})
+	if _, stderr, err := runShell(t, dir, tarInstallScript(target, archive, "server", sum, "1")); err != nil {
+		t.Fatalf("install: %v (stderr: %s)", err, stderr)
+	}
+
+	if info, err := os.Lstat(target); err != nil || !info.Mode().IsRegular() {
+		t.Fatalf("target should be a regular file: %v", err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "binary" {
+		t.Errorf("target = %q", got)
+	}
+	if got, _ := os.ReadFile(outside); string(got) != "outside" {
+		t.Errorf("file behind the old link changed: %q", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("staging files left behind: %v", entries)
+	}
+}
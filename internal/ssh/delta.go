@@ -0,0 +1,422 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yejune/gorelay/internal/sftp"
+)
+
+// Files smaller than this are always sent whole
+const deltaMinSize = 1 << 20
+
+// Remote files are split into at most about this many blocks
+// (each block costs a few processes on the remote while hashing)
+const deltaMaxBlocks = 1000
+
+// errDeltaNotWorth means too little of the remote file is reusable
+var errDeltaNotWorth = errors.New("delta would not save enough")
+
+// cksumTable is the CRC-32 table used by POSIX cksum (polynomial 0x04C11DB7, MSB first)
+var cksumTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+func crcUpdate(crc uint32, b byte) uint32 {
+	return crc<<8 ^ cksumTable[byte(crc>>24)^b]
+}
+
+// rollingCksum computes the POSIX cksum of every window of a fixed size.
+// The register starts at zero, so a byte leaving the window can be removed
+// by XOR-ing out its contribution after size-1 further shifts.
+type rollingCksum struct {
+	size   int
+	crc    uint32
+	out    [256]uint32 // Contribution of a byte at the window's first position
+	suffix []byte      // Length bytes cksum appends before the final complement
+}
+
+func newRollingCksum(size int) *rollingCksum {
+	r := &rollingCksum{size: size}
+
+	// 비트별 기여도를 구한 뒤 선형 결합
+	var bits [8]uint32
+	for bit := 0; bit < 8; bit++ {
+		crc := crcUpdate(0, byte(1)<<bit)
+		for i := 1; i < size; i++ {
+			crc = crcUpdate(crc, 0)
+		}
+		bits[bit] = crc
+	}
+	for v := 0; v < 256; v++ {
+		for bit := 0; bit < 8; bit++ {
+			if v&(1<<bit) != 0 {
+				r.out[v] ^= bits[bit]
+			}
+		}
+	}
+
+	for n := size; n > 0; n >>= 8 {
+		r.suffix = append(r.suffix, byte(n))
+	}
+	return r
+}
+
+func (r *rollingCksum) reset(window []byte) {
+	r.crc = 0
+	for _, b := range window {
+		r.crc = crcUpdate(r.crc, b)
+	}
+}
+
+func (r *rollingCksum) roll(out, in byte) {
+	r.crc = crcUpdate(r.crc^r.out[out], in)
+}
+
+// sum returns the value cksum prints for the current window
+func (r *rollingCksum) sum() uint32 {
+	crc := r.crc
+	for _, b := range r.suffix {
+		crc = crcUpdate(crc, b)
+	}
+	return ^crc
+}
+
+// blockSignature describes the remote file: weak and strong hashes of each full block
+type blockSignature struct {
+	blockSize int
+	size      int64
+	weak      map[uint32][]int
+	strong    []string
+}
+
+// remoteSignature hashes the remote file block by block using only POSIX
+// tools (dd, cksum) and sha256sum/shasum, so rsync is not needed
+func (c *Client) remoteSignature(remotePath string) (*blockSignature, error) {
+	script := strings.Join([]string{
+		remoteHashCmd,
+		fmt.Sprintf("f=%s", shellQuote(remotePath)),
+		`[ -f "$f" ] || exit 3`,
+		`s=$(wc -c < "$f" | tr -d ' ')`,
+		fmt.Sprintf(`b=$(( (s / %d + 4095) / 4096 * 4096 )); [ $b -lt 65536 ] && b=65536`, deltaMaxBlocks),
+		`echo "B $b $s"`,
+		`t=$(mktemp) || exit 1; trap 'rm -f "$t"' EXIT`,
+		`i=0; n=$(( s / b ))`,
+		`while [ $i -lt $n ]; do dd if="$f" of="$t" bs=$b skip=$i count=1 2>/dev/null; echo "$(cksum < "$t") $($h < "$t")"; i=$((i + 1)); done`,
+	}, "\n")
+
+	session, err := c.newSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	defer c.closeSession(session)
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+
+	if err := session.Start(script); err != nil {
+		return nil, fmt.Errorf("failed to hash remote blocks: %w", err)
+	}
+
+	sig := &blockSignature{weak: make(map[uint32][]int)}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "B" {
+			sig.blockSize, _ = strconv.Atoi(fields[1])
+			sig.size, _ = strconv.ParseInt(fields[2], 10, 64)
+			continue
+		}
+		// <crc> <길이> <sha256> [-]
+		if len(fields) < 3 {
+			continue
+		}
+		weak, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			continue
+		}
+		index := len(sig.strong)
+		sig.weak[uint32(weak)] = append(sig.weak[uint32(weak)], index)
+		sig.strong = append(sig.strong, fields[2])
+	}
+	if err := session.Wait(); err != nil {
+		return nil, fmt.Errorf("failed to hash remote blocks: %w (stderr: %s)", err, stderr.String())
+	}
+	if sig.blockSize == 0 {
+		return nil, fmt.Errorf("failed to hash remote blocks: no output")
+	}
+	return sig, nil
+}
+
+// deltaOp either copies blocks of the old remote file or inserts literal bytes
+type deltaOp struct {
+	copyStart, copyCount int   // Blocks to copy (count 0: literal)
+	litOffset, litLength int64 // Range in the literal file
+}
+
+// computeDelta finds the remote blocks inside the local file at any offset
+// (rsync's rolling checksum) and writes everything else to literals
+func computeDelta(local *os.File, localSize int64, sig *blockSignature, literals io.Writer) ([]deltaOp, int64, error) {
+	var ops []deltaOp
+	var litTotal int64
+	blockSize := int64(sig.blockSize)
+
+	flushLiteral := func(from, to int64) error {
+		if to <= from {
+			return nil
+		}
+		if _, err := io.Copy(literals, io.NewSectionReader(local, from, to-from)); err != nil {
+			return err
+		}
+		// 바로 앞도 리터럴이면 합침
+		if n := len(ops); n > 0 && ops[n-1].copyCount == 0 {
+			ops[n-1].litLength += to - from
+		} else {
+			ops = append(ops, deltaOp{litOffset: litTotal, litLength: to - from})
+		}
+		litTotal += to - from
+		return nil
+	}
+
+	addCopy := func(block int) {
+		// 연속된 블록은 하나의 dd로
+		if n := len(ops); n > 0 && ops[n-1].copyCount > 0 && ops[n-1].copyStart+ops[n-1].copyCount == block {
+			ops[n-1].copyCount++
+			return
+		}
+		ops = append(ops, deltaOp{copyStart: block, copyCount: 1})
+	}
+
+	if len(sig.strong) == 0 {
+		return nil, 0, errDeltaNotWorth
+	}
+
+	window := &fileWindow{f: local, size: localSize}
+	roller := newRollingCksum(sig.blockSize)
+
+	var literalStart, pos int64
+	needReset := true
+	for pos+blockSize <= localSize {
+		if needReset {
+			data, err := window.slice(pos, blockSize)
+			if err != nil {
+				return nil, 0, err
+			}
+			roller.reset(data)
+			needReset = false
+		}
+
+		if candidates, ok := sig.weak[roller.sum()]; ok {
+			data, err := window.slice(pos, blockSize)
+			if err != nil {
+				return nil, 0, err
+			}
+			sum := sha256.Sum256(data)
+			strong := hex.EncodeToString(sum[:])
+			matched := -1
+			for _, block := range candidates {
+				if sig.strong[block] == strong {
+					matched = block
+					break
+				}
+			}
+			if matched >= 0 {
+				if err := flushLiteral(literalStart, pos); err != nil {
+					return nil, 0, err
+				}
+				addCopy(matched)
+				pos += blockSize
+				literalStart = pos
+				needReset = true
+				continue
+			}
+		}
+
+		if pos+blockSize == localSize {
+			break
+		}
+		data, err := window.slice(pos, blockSize+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		roller.roll(data[0], data[blockSize])
+		pos++
+	}
+
+	if err := flushLiteral(literalStart, localSize); err != nil {
+		return nil, 0, err
+	}
+	return ops, litTotal, nil
+}
+
+// fileWindow serves byte ranges of a file from a large buffer, so rolling
+// over a big file reads it sequentially without loading it whole
+type fileWindow struct {
+	f     *os.File
+	size  int64
+	buf   []byte
+	start int64
+}
+
+const fileWindowChunk = 8 << 20
+
+func (w *fileWindow) slice(off, n int64) ([]byte, error) {
+	if off >= w.start && off+n <= w.start+int64(len(w.buf)) {
+		return w.buf[off-w.start : off-w.start+n], nil
+	}
+
+	want := n + fileWindowChunk
+	if off+want > w.size {
+		want = w.size - off
+	}
+	if int64(cap(w.buf)) < want {
+		w.buf = make([]byte, want)
+	}
+	w.buf = w.buf[:want]
+	if _, err := w.f.ReadAt(w.buf, off); err != nil && err != io.EOF {
+		return nil, err
+	}
+	w.start = off
+	return w.buf[:n], nil
+}
+
+// sendDelta updates remotePath to match localPath by sending only the bytes
+// the remote copy lacks. The new file is assembled next to the old one,
+// checked against the local SHA256 and renamed into place.
+func (c *Client) sendDelta(localPath, remotePath, localHash string, preserve Preserve) (int64, error) {
+	local, err := os.Open(localPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read local file: %w", err)
+	}
+	defer local.Close()
+
+	info, err := local.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat local file: %w", err)
+	}
+
+	sig, err := c.remoteSignature(remotePath)
+	if err != nil {
+		return 0, err
+	}
+
+	// 리터럴은 로컬 임시 파일에 모음 (메모리 사용량 일정)
+	literals, err := os.CreateTemp("", "gorelay-delta-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(literals.Name())
+	defer literals.Close()
+
+	ops, litTotal, err := computeDelta(local, info.Size(), sig, literals)
+	if err != nil {
+		return 0, err
+	}
+	if litTotal > info.Size()*8/10 {
+		return 0, errDeltaNotWorth
+	}
+
+	t, err := c.transport()
+	if err != nil {
+		return 0, err
+	}
+
+	id := randomID()
+	dir := path.Dir(remotePath)
+	remoteLit := path.Join(dir, ".gorelay-lit-"+id)
+	remoteNew := path.Join(dir, ".gorelay-new-"+id)
+	c.trackTemp(remoteLit)
+	c.trackTemp(remoteNew)
+
+	if _, err := literals.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if err := t.writeFile(remoteLit, literals, litTotal, fileAttrs{Mode: 0600}); err != nil {
+		return 0, fmt.Errorf("failed to upload delta: %w", err)
+	}
+
+	script := deltaScript(remotePath, remoteNew, remoteLit, sig.blockSize, ops, localHash, preserve.attrs(info))
+
+	session, err := c.newSession()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create session: %w", err)
+	}
+	defer c.closeSession(session)
+
+	var stderr bytes.Buffer
+	session.Stdin = strings.NewReader(script)
+	session.Stderr = &stderr
+	if err := session.Run("sh -s"); err != nil {
+		return 0, fmt.Errorf("failed to apply delta: %w (stderr: %s)", err, stderr.String())
+	}
+	c.untrackTemp(remoteLit)
+	c.untrackTemp(remoteNew)
+
+	return litTotal, nil
+}
+
+// deltaScript assembles the new file from dd copies of the old one and slices
+// of the literal file, then swaps it in only if its SHA256 matches
+func deltaScript(oldPath, newPath, litPath string, blockSize int, ops []deltaOp, hash string, attrs fileAttrs) string {
+	var script strings.Builder
+	fmt.Fprintf(&script, "set -e\n%s\nold=%s; new=%s; lit=%s\n{\n", remoteHashCmd, shellQuote(oldPath), shellQuote(newPath), shellQuote(litPath))
+	for _, op := range ops {
+		if op.copyCount > 0 {
+			fmt.Fprintf(&script, "dd if=\"$old\" bs=%d skip=%d count=%d 2>/dev/null\n", blockSize, op.copyStart, op.copyCount)
+		} else {
+			fmt.Fprintf(&script, "tail -c +%d \"$lit\" | head -c %d\n", op.litOffset+1, op.litLength)
+		}
+	}
+	script.WriteString("} < /dev/null > \"$new\"\n")
+	fmt.Fprintf(&script, "[ \"$($h < \"$new\" | cut -d' ' -f1)\" = %s ] || { echo \"delta checksum mismatch\" >&2; rm -f \"$new\" \"$lit\"; exit 1; }\n", hash)
+	fmt.Fprintf(&script, "chmod %04o \"$new\"\n", sftp.PosixMode(attrs.Mode))
+	if !attrs.ModTime.IsZero() {
+		fmt.Fprintf(&script, "TZ=UTC touch -m -t %s \"$new\"\n", attrs.ModTime.UTC().Format("200601021504.05"))
+	}
+	script.WriteString("mv -f \"$new\" \"$old\"\nrm -f \"$lit\"\n")
+	return script.String()
+}
+
+// deltaOrFull tries a delta update of a changed file and falls back to a
+// full upload when the delta fails or would not save enough
+func (c *Client) deltaOrFull(localPath, remotePath, localHash string, preserve Preserve) error {
+	start := time.Now()
+	sent, err := c.sendDelta(localPath, remotePath, localHash, preserve)
+	if err == nil {
+		if c.verbose {
+			fmt.Printf("      Delta: %s (sent %d bytes in %s)\n", path.Base(remotePath), sent, time.Since(start).Round(time.Millisecond))
+		}
+		return nil
+	}
+
+	if c.verbose {
+		fmt.Printf("      Delta skipped for %s (%v), sending whole file\n", path.Base(remotePath), err)
+	}
+	return c.sendFile(localPath, remotePath, preserve)
+}
@@ -0,0 +1,234 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cksum computes the POSIX cksum of data from scratch
+func cksum(data []byte) uint32 {
+	r := newRollingCksum(len(data))
+	r.reset(data)
+	return r.sum()
+}
+
+func TestCksumVectors(t *testing.T) {
+	tests := []struct {
+		data string
+		want uint32
+	}{
+		{"", 4294967295},
+		{"a", 1220704766},
+		{"123456789", 930766865},
+	}
+	for _, tt := range tests {
+		if got := cksum([]byte(tt.data)); got != tt.want {
+			t.Errorf("cksum(%q) = %d, want %d", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestCksumMatchesSystemCksum(t *testing.T) {
+	if _, err := exec.LookPath("cksum"); err != nil {
+		t.Skip("cksum not installed")
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	// 길이 접미사가 1, 2, 3바이트가 되는 크기를 포함
+	for _, size := range []int{1, 255, 256, 4096, 65536, 70000} {
+		data := make([]byte, size)
+		rng.Read(data)
+
+		cmd := exec.Command("cksum")
+		cmd.Stdin = bytes.NewReader(data)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := strconv.ParseUint(strings.Fields(string(out))[0], 10, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cksum(data); got != uint32(want) {
+			t.Errorf("size %d: cksum = %d, system cksum = %d", size, got, want)
+		}
+	}
+}
+
+func TestRollingCksumMatchesFromScratch(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, size := range []int{1, 2, 17, 256, 1000} {
+		data := make([]byte, size*3+5)
+		rng.Read(data)
+
+		roller := newRollingCksum(size)
+		roller.reset(data[:size])
+		for pos := 0; ; pos++ {
+			if got, want := roller.sum(), cksum(data[pos:pos+size]); got != want {
+				t.Fatalf("size %d, offset %d: rolled %d, from scratch %d", size, pos, got, want)
+			}
+			if pos+size == len(data) {
+				break
+			}
+			roller.roll(data[pos], data[pos+size])
+		}
+	}
+}
+
+// localSignature builds the signature remoteSignature would return for old
+func localSignature(old []byte, blockSize int) *blockSignature {
+	sig := &blockSignature{blockSize: blockSize, size: int64(len(old)), weak: make(map[uint32][]int)}
+	for i := 0; (i+1)*blockSize <= len(old); i++ {
+		block := old[i*blockSize : (i+1)*blockSize]
+		sum := sha256.Sum256(block)
+		weak := cksum(block)
+		sig.weak[weak] = append(sig.weak[weak], i)
+		sig.strong = append(sig.strong, hex.EncodeToString(sum[:]))
+	}
+	return sig
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	const blockSize = 64
+	rng := rand.New(rand.NewSource(3))
+	random := func(n int) []byte {
+		b := make([]byte, n)
+		rng.Read(b)
+		return b
+	}
+	old := random(blockSize*40 + 13)
+
+	tests := []struct {
+		name string
+		new  []byte
+	}{
+		{"unchanged", old},
+		{"insert", concat(old[:1000], random(7), old[1000:])},
+		{"delete", concat(old[:500], old[900:])},
+		{"modify", concat(old[:2000], []byte("changed"), old[2007:])},
+		{"prepend and append", concat(random(3), old, random(100))},
+		{"reordered", concat(old[1280:], old[:1280])},
+		{"unrelated", random(len(old))},
+	}
+
+	_, shErr := exec.LookPath("sh")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			newPath := filepath.Join(dir, "local")
+			if err := os.WriteFile(newPath, tt.new, 0644); err != nil {
+				t.Fatal(err)
+			}
+			local, err := os.Open(newPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer local.Close()
+
+			var literals bytes.Buffer
+			ops, litTotal, err := computeDelta(local, int64(len(tt.new)), localSignature(old, blockSize), &literals)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if litTotal != int64(literals.Len()) {
+				t.Fatalf("litTotal = %d, literals hold %d bytes", litTotal, literals.Len())
+			}
+			// 끝의 불완전한 블록만 리터럴로 보냄
+			if tt.name == "unchanged" && litTotal != int64(len(old)%blockSize) {
+				t.Errorf("unchanged file sent %d literal bytes, want %d", litTotal, len(old)%blockSize)
+			}
+
+			if got := applyDelta(old, literals.Bytes(), blockSize, ops); !bytes.Equal(got, tt.new) {
+				t.Fatalf("applying the delta gave %d bytes, want %d", len(got), len(tt.new))
+			}
+
+			if shErr != nil {
+				return
+			}
+			// 원격에서 실행될 스크립트로 재구성
+			oldPath := filepath.Join(dir, "old file")
+			litPath := filepath.Join(dir, "lit")
+			if err := os.WriteFile(oldPath, old, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(litPath, literals.Bytes(), 0600); err != nil {
+				t.Fatal(err)
+			}
+			sum := sha256.Sum256(tt.new)
+			mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			script := deltaScript(oldPath, filepath.Join(dir, "new"), litPath, blockSize, ops, hex.EncodeToString(sum[:]), fileAttrs{Mode: 0640, ModTime: mtime})
+
+			cmd := exec.Command("sh", "-s")
+			cmd.Stdin = strings.NewReader(script)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("delta script failed: %v\n%s", err, out)
+			}
+			got, err := os.ReadFile(oldPath)
+			if err != nil || !bytes.Equal(got, tt.new) {
+				t.Fatalf("delta script produced %d bytes (err %v), want %d", len(got), err, len(tt.new))
+			}
+			info, _ := os.Stat(oldPath)
+			if info.Mode().Perm() != 0640 || !info.ModTime().Equal(mtime) {
+				t.Errorf("mode %v, mtime %v; want 0640, %v", info.Mode().Perm(), info.ModTime(), mtime)
+			}
+			if _, err := os.Stat(litPath); !os.IsNotExist(err) {
+				t.Error("literal file was not removed")
+			}
+		})
+	}
+}
+
+func TestDeltaScriptRejectsChecksumMismatch(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old")
+	litPath := filepath.Join(dir, "lit")
+	os.WriteFile(oldPath, []byte("old"), 0644)
+	os.WriteFile(litPath, []byte("new"), 0600)
+
+	ops := []deltaOp{{litOffset: 0, litLength: 3}}
+	script := deltaScript(oldPath, filepath.Join(dir, "new"), litPath, 64, ops, strings.Repeat("0", 64), fileAttrs{Mode: 0644})
+	cmd := exec.Command("sh", "-s")
+	cmd.Stdin = strings.NewReader(script)
+	if err := cmd.Run(); err == nil {
+		t.Fatal("expected the script to fail")
+	}
+	if got, _ := os.ReadFile(oldPath); string(got) != "old" {
+		t.Errorf("old file = %q, want it untouched", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new")); !os.IsNotExist(err) {
+		t.Error("new file was left behind")
+	}
+}
+
+// applyDelta rebuilds the new file from old blocks and literals
+func applyDelta(old, literals []byte, blockSize int, ops []deltaOp) []byte {
+	var out []byte
+	for _, op := range ops {
+		if op.copyCount > 0 {
+			out = append(out, old[op.copyStart*blockSize:(op.copyStart+op.copyCount)*blockSize]...)
+		} else {
+			out = append(out, literals[op.litOffset:op.litOffset+op.litLength]...)
+		}
+	}
+	return out
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
@@ -0,0 +1,224 @@
+package ssh
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FetchResult summarizes a download
+type FetchResult struct {
+	Files int
+	Bytes int64 // Size of the downloaded archive
+}
+
+// Download copies a remote file or directory into localDir.
+// A directory's contents land directly in localDir, a file as localDir/<name>.
+// The remote side packs a snapshot into a private temp archive and prints
+// its SHA256; the archive is only extracted if the download hashes the same.
+func (c *Client) Download(remotePath, localDir string) (FetchResult, error) {
+	var result FetchResult
+
+	t, err := c.transport()
+	if err != nil {
+		return result, err
+	}
+
+	remoteTar := fmt.Sprintf("/tmp/gorelay-%s.tar.gz", randomID())
+	c.trackTemp(remoteTar)
+	defer func() {
+		c.Run(fmt.Sprintf("rm -f %s", shellQuote(remoteTar)), io.Discard, io.Discard)
+		c.untrackTemp(remoteTar)
+	}()
+
+	// 파일이면 상위 디렉토리에서 이름으로, 디렉토리면 내용 전체를 묶음
+	script := strings.Join([]string{
+		remoteHashCmd,
+		fmt.Sprintf("p=%s; t=%s", shellQuote(remotePath), shellQuote(remoteTar)),
+		`[ -e "$p" ] || { echo "no such file or directory: $p" >&2; exit 1; }`,
+		`umask 077`,
+		`if [ -d "$p" ]; then (cd "$p" && tar -czf "$t" .); else (cd "$(dirname "$p")" && tar -czf "$t" "$(basename "$p")"); fi || exit 1`,
+		`$h "$t"`,
+	}, "\n")
+	var stdout, stderr bytes.Buffer
+	if err := c.Run(script, &stdout, &stderr); err != nil {
+		return result, fmt.Errorf("failed to pack remote files: %w (stderr: %s)", err, stderr.String())
+	}
+	remoteHash := strings.Fields(stdout.String())
+	if len(remoteHash) == 0 {
+		return result, fmt.Errorf("failed to pack remote files: no checksum")
+	}
+
+	if err := os.MkdirAll(localDir, 0755); err != nil {
+		return result, fmt.Errorf("failed to create local directory: %w", err)
+	}
+
+	// 검증 전에는 풀지 않도록 로컬 임시 파일로 받음
+	archive, err := os.CreateTemp(localDir, ".gorelay-fetch-*")
+	if err != nil {
+		return result, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	hash := sha256.New()
+	result.Bytes, err = t.readFile(remoteTar, io.MultiWriter(archive, hash))
+	if err != nil {
+		return result, err
+	}
+	localHash := hex.EncodeToString(hash.Sum(nil))
+	if localHash != remoteHash[0] {
+		return result, fmt.Errorf("checksum mismatch: local=%s, remote=%s", localHash, remoteHash[0])
+	}
+	if c.verbose {
+		fmt.Printf("      Archive: %d bytes, SHA256 %s\n", result.Bytes, localHash)
+	}
+
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return result, err
+	}
+	result.Files, err = c.extractArchive(archive, localDir)
+	return result, err
+}
+
+// extractArchive unpacks a tar.gz into dir. Entries may not leave dir, and
+// symlinks are created last so no file is ever written through one; links
+// left in the way by an earlier fetch are removed before writing below them.
+func (c *Client) extractArchive(r io.Reader, dir string) (int, error) {
+	gzReader, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gzReader.Close()
+
+	type link struct{ target, path string }
+	var links []link
+	var dirTimes []*tar.Header
+	files := 0
+
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return files, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := filepath.FromSlash(strings.TrimPrefix(header.Name, "./"))
+		if name == "" || name == "." {
+			continue
+		}
+		if !filepath.IsLocal(name) {
+			return files, fmt.Errorf("refusing to extract %s outside %s", header.Name, dir)
+		}
+		target := filepath.Join(dir, name)
+		mode := os.FileMode(header.Mode).Perm()
+
+		// 이전 fetch가 만든 링크를 통해 dir 밖에 쓰지 않도록 경로의 링크를 지움
+		parent := filepath.Dir(name)
+		if header.Typeflag == tar.TypeDir {
+			parent = name
+		}
+		if err := removeSymlinks(dir, parent); err != nil {
+			return files, err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return files, fmt.Errorf("failed to create directory: %w", err)
+			}
+			dirTimes = append(dirTimes, header)
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return files, fmt.Errorf("failed to create directory: %w", err)
+			}
+			// 이전 실행이 남긴 링크를 따라가지 않도록 먼저 지움
+			if info, err := os.Lstat(target); err == nil && !info.Mode().IsRegular() && !info.IsDir() {
+				os.Remove(target)
+			}
+			if err := writeLocalFile(target, tarReader, mode, header.ModTime); err != nil {
+				return files, err
+			}
+			files++
+			if c.verbose {
+				fmt.Printf("      Download: %s\n", filepath.ToSlash(name))
+			}
+		case tar.TypeSymlink:
+			links = append(links, link{target: header.Linkname, path: target})
+		default:
+			if c.verbose {
+				fmt.Printf("      Skip (not a regular file): %s\n", filepath.ToSlash(name))
+			}
+		}
+	}
+
+	for _, l := range links {
+		os.Remove(l.path)
+		if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+			return files, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.Symlink(l.target, l.path); err != nil {
+			return files, fmt.Errorf("failed to create symlink: %w", err)
+		}
+	}
+
+	// 디렉토리 시간은 안의 파일을 다 쓴 뒤에 설정
+	for _, header := range dirTimes {
+		name := filepath.FromSlash(strings.TrimPrefix(header.Name, "./"))
+		os.Chtimes(filepath.Join(dir, name), header.ModTime, header.ModTime)
+	}
+	return files, nil
+}
+
+// removeSymlinks removes any symlink among dir/name and its parents below dir,
+// so the path resolves to real directories inside dir (or does not exist yet)
+func removeSymlinks(dir, name string) error {
+	if name == "." {
+		return nil
+	}
+	p := dir
+	for _, part := range strings.Split(name, string(filepath.Separator)) {
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", p, err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(p); err != nil {
+				return fmt.Errorf("failed to replace symlink %s: %w", p, err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+func writeLocalFile(target string, r io.Reader, mode os.FileMode, modTime time.Time) error {
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	os.Chmod(target, mode)
+	return os.Chtimes(target, modTime, modTime)
+}
@@ -0,0 +1,125 @@
+package ssh
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// tarEntry is one member of a test archive (a directory when name ends in /)
+type tarEntry struct {
+	name, content, link string
+}
+
+func buildArchive(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, ModTime: time.Unix(1700000000, 0)}
+		switch {
+		case e.link != "":
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = e.link
+		case e.name[len(e.name)-1] == '/':
+			header.Typeflag = tar.TypeDir
+			header.Mode = 0755
+		default:
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(len(e.content))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractArchiveRejectsEscapes(t *testing.T) {
+	c := &Client{}
+	for _, name := range []string{"../evil", "a/../../evil", "/etc/evil"} {
+		archive := buildArchive(t, []tarEntry{{name: name, content: "x"}})
+		if _, err := c.extractArchive(archive, t.TempDir()); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestExtractArchiveDoesNotFollowOldSymlinks(t *testing.T) {
+	c := &Client{}
+	dir := t.TempDir()
+	outside := t.TempDir()
+
+	// 첫 fetch: 원격의 assets와 data/cache가 dir 밖을 가리키는 링크
+	first := buildArchive(t, []tarEntry{
+		{name: "./"},
+		{name: "./assets", link: outside},
+		{name: "./data/"},
+		{name: "./data/cache", link: "../../" + filepath.Base(outside)},
+	})
+	if _, err := c.extractArchive(first, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// 다음 fetch: 같은 이름이 이제는 실제 디렉토리
+	second := buildArchive(t, []tarEntry{
+		{name: "./"},
+		{name: "./assets/"},
+		{name: "./assets/app.js", content: "new"},
+		{name: "./data/"},
+		{name: "./data/cache/sub/"},
+		{name: "./data/cache/sub/file", content: "new"},
+	})
+	files, err := c.extractArchive(second, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files != 2 {
+		t.Errorf("files = %d, want 2", files)
+	}
+
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("files were written outside the fetch directory: %v", entries)
+	}
+	for _, p := range []string{"assets", "data/cache"} {
+		info, err := os.Lstat(filepath.Join(dir, p))
+		if err != nil || !info.IsDir() {
+			t.Errorf("%s should be a real directory now (err %v)", p, err)
+		}
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "assets", "app.js")); string(got) != "new" {
+		t.Errorf("assets/app.js = %q", got)
+	}
+}
+
+func TestExtractArchiveKeepsSymlinks(t *testing.T) {
+	c := &Client{}
+	dir := t.TempDir()
+	archive := buildArchive(t, []tarEntry{
+		{name: "./current", link: "releases/2"},
+		{name: "./releases/2/app", content: "v2"},
+	})
+	if _, err := c.extractArchive(archive, dir); err != nil {
+		t.Fatal(err)
+	}
+	if target, err := os.Readlink(filepath.Join(dir, "current")); err != nil || target != "releases/2" {
+		t.Errorf("current -> %q, %v", target, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "current", "app")); string(got) != "v2" {
+		t.Errorf("current/app = %q", got)
+	}
+}
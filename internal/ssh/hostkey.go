@@ -0,0 +1,235 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Host key checking modes
+const (
+	HostKeyStrict    = "strict"     // unknown or changed keys are rejected
+	HostKeyAcceptNew = "accept-new" // unknown keys are appended, changed keys are rejected
+	HostKeyOff       = "off"        // no verification (insecure)
+)
+
+// known_hosts 파일 추가 기록은 병렬 접속 간에 직렬화
+var knownHostsMu sync.Mutex
+
+// hostKeyCallback builds the host key verification for a connection.
+// A pinned fingerprint takes precedence over known_hosts files.
+func hostKeyCallback(opts Options) (ssh.HostKeyCallback, error) {
+	mode := opts.HostKeyCheck
+	if mode == "" {
+		mode = HostKeyStrict
+	}
+
+	switch mode {
+	case HostKeyStrict, HostKeyAcceptNew:
+	case HostKeyOff:
+		return ssh.InsecureIgnoreHostKey(), nil
+	default:
+		return nil, fmt.Errorf("invalid host_key_check: %s (expected strict, accept-new or off)", mode)
+	}
+
+	if opts.HostKey != "" {
+		pinned := normalizeFingerprint(opts.HostKey)
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			// 호스트 인증서는 인증서 안의 키로 비교
+			if cert, ok := key.(*ssh.Certificate); ok {
+				key = cert.Key
+			}
+			actual := ssh.FingerprintSHA256(key)
+			if actual != pinned {
+				return fmt.Errorf("host key mismatch for %s: server presented %s %s, pinned %s", hostname, key.Type(), actual, pinned)
+			}
+			return nil
+		}, nil
+	}
+
+	files := knownHostsFiles(opts.KnownHosts)
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsMu.Lock()
+		defer knownHostsMu.Unlock()
+
+		// accept-new로 다른 접속이 추가한 키도 보이도록 매번 다시 읽음
+		existing := existingFiles(files)
+		check, err := knownhosts.New(existing...)
+		if err != nil {
+			return fmt.Errorf("failed to read known_hosts: %w", err)
+		}
+
+		err = check(hostname, remote, key)
+		if cert, ok := key.(*ssh.Certificate); ok && err != nil {
+			// known_hosts의 CA가 서명했는데 검증 실패하면 거부
+			if certAuthorities(existing)[string(cert.SignatureKey.Marshal())] {
+				return fmt.Errorf("host certificate for %s not trusted: %w", hostname, err)
+			}
+			// 모르는 CA면 OpenSSH처럼 인증서 안의 키를 일반 호스트 키로 확인
+			key = cert.Key
+			err = check(hostname, remote, key)
+		}
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+
+		fingerprint := ssh.FingerprintSHA256(key)
+		if len(keyErr.Want) > 0 {
+			known := keyErr.Want[0]
+			return fmt.Errorf("host key mismatch for %s: server presented %s %s, but %s:%d has %s %s (possible man-in-the-middle attack)",
+				hostname, key.Type(), fingerprint, known.Filename, known.Line, known.Key.Type(), ssh.FingerprintSHA256(known.Key))
+		}
+
+		if mode != HostKeyAcceptNew {
+			return fmt.Errorf("unknown host key for %s: %s %s (add it to %s or set host_key_check: accept-new)",
+				hostname, key.Type(), fingerprint, files[len(files)-1])
+		}
+
+		return appendKnownHost(files[len(files)-1], hostname, key)
+	}, nil
+}
+
+// hostKeyAlgorithms returns the algorithms of keys already known for addr,
+// so the server offers a key we can actually verify. Hosts covered by a
+// @cert-authority entry prefer host certificates.
+func hostKeyAlgorithms(opts Options, addr string) []string {
+	if opts.HostKey != "" || opts.HostKeyCheck == HostKeyOff {
+		return nil
+	}
+
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	files := existingFiles(knownHostsFiles(opts.KnownHosts))
+	check, err := knownhosts.New(files...)
+	if err != nil {
+		return nil
+	}
+	authorities := certAuthorities(files)
+
+	// 존재하지 않는 키로 조회하면 KeyError.Want에 알려진 키 목록이 담김
+	var keyErr *knownhosts.KeyError
+	if err := check(addr, &net.TCPAddr{IP: net.IPv4zero}, probeKey{}); !errors.As(err, &keyErr) {
+		return nil
+	}
+
+	var certAlgos, keyAlgos []string
+	for _, known := range keyErr.Want {
+		if authorities[string(known.Key.Marshal())] {
+			certAlgos = certAlgorithms
+			continue
+		}
+		keyAlgos = append(keyAlgos, algorithmsForKeyType(known.Key.Type())...)
+	}
+	// CA만 알고 있으면 호스트 키 종류를 모르므로 기본 목록 사용 (인증서 우선)
+	if len(keyAlgos) == 0 {
+		return nil
+	}
+
+	var algos []string
+	seen := make(map[string]bool)
+	for _, algo := range append(certAlgos, keyAlgos...) {
+		if !seen[algo] {
+			seen[algo] = true
+			algos = append(algos, algo)
+		}
+	}
+	return algos
+}
+
+// 호스트 인증서 알고리즘 (OpenSSH 선호 순서)
+var certAlgorithms = []string{
+	ssh.CertAlgoED25519v01,
+	ssh.CertAlgoECDSA256v01,
+	ssh.CertAlgoECDSA384v01,
+	ssh.CertAlgoECDSA521v01,
+	ssh.CertAlgoRSASHA512v01,
+	ssh.CertAlgoRSASHA256v01,
+}
+
+// certAuthorities collects the keys of @cert-authority lines in files
+func certAuthorities(files []string) map[string]bool {
+	authorities := make(map[string]bool)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 4 || fields[0] != "@cert-authority" {
+				continue
+			}
+			key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.Join(fields[2:], " ")))
+			if err == nil {
+				authorities[string(key.Marshal())] = true
+			}
+		}
+	}
+	return authorities
+}
+
+func algorithmsForKeyType(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+// knownHostsFiles returns ~/.ssh/known_hosts followed by the per-server file.
+// The last entry is where accept-new records new keys.
+func knownHostsFiles(extra string) []string {
+	files := []string{expandPath("~/.ssh/known_hosts")}
+	if extra != "" {
+		files = append(files, expandPath(extra))
+	}
+	return files
+}
+
+func existingFiles(files []string) []string {
+	var result []string
+	for _, f := range files {
+		if _, err := os.Stat(f); err == nil {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func appendKnownHost(path, hostname string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open known_hosts: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{hostname}, key)); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
+
+func normalizeFingerprint(fp string) string {
+	fp = strings.TrimSpace(fp)
+	fp = strings.TrimPrefix(fp, "SHA256:")
+	return "SHA256:" + strings.TrimRight(fp, "=")
+}
+
+// probeKey is a placeholder key that never matches a known_hosts entry.
+type probeKey struct{}
+
+func (probeKey) Type() string                                 { return "gorelay-probe" }
+func (probeKey) Marshal() []byte                              { return []byte("gorelay-probe") }
+func (probeKey) Verify(data []byte, sig *ssh.Signature) error { return errors.New("probe key") }
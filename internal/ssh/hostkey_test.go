@@ -0,0 +1,132 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newTestKey(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func newHostCert(t *testing.T, key ssh.PublicKey, ca ssh.Signer, principal string) *ssh.Certificate {
+	t.Helper()
+	cert := &ssh.Certificate{
+		Key:             key,
+		CertType:        ssh.HostCert,
+		ValidPrincipals: []string{principal},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	const host = "host.example.com"
+	hostname := host + ":22"
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+
+	hostKey := newTestKey(t).PublicKey()
+	otherKey := newTestKey(t).PublicKey()
+	ca := newTestKey(t)
+	otherCA := newTestKey(t)
+
+	knownLine := knownhosts.Line([]string{hostname}, hostKey)
+	caLine := "@cert-authority *.example.com " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey())))
+
+	tests := []struct {
+		name     string
+		mode     string
+		pinned   string
+		known    []string
+		key      ssh.PublicKey
+		wantErr  string // "" when the key must be accepted
+		recorded bool   // accept-new appended the key
+	}{
+		{name: "strict known", mode: HostKeyStrict, known: []string{knownLine}, key: hostKey},
+		{name: "default is strict", known: nil, key: hostKey, wantErr: "unknown host key"},
+		{name: "strict unknown", mode: HostKeyStrict, key: hostKey, wantErr: "unknown host key"},
+		{name: "strict changed", mode: HostKeyStrict, known: []string{knownLine}, key: otherKey, wantErr: "host key mismatch"},
+		{name: "accept-new unknown", mode: HostKeyAcceptNew, key: hostKey, recorded: true},
+		{name: "accept-new known", mode: HostKeyAcceptNew, known: []string{knownLine}, key: hostKey},
+		{name: "accept-new changed", mode: HostKeyAcceptNew, known: []string{knownLine}, key: otherKey, wantErr: "host key mismatch"},
+		{name: "off", mode: HostKeyOff, known: []string{knownLine}, key: otherKey},
+		{name: "pinned match", pinned: ssh.FingerprintSHA256(hostKey), key: hostKey},
+		{name: "pinned match without prefix", pinned: strings.TrimPrefix(ssh.FingerprintSHA256(hostKey), "SHA256:") + "=", key: hostKey},
+		{name: "pinned mismatch", pinned: ssh.FingerprintSHA256(hostKey), key: otherKey, wantErr: "host key mismatch"},
+		{name: "pinned ignores known_hosts", pinned: ssh.FingerprintSHA256(otherKey), known: []string{knownLine}, key: otherKey},
+		{name: "pinned certificate", pinned: ssh.FingerprintSHA256(hostKey), key: newHostCert(t, hostKey, otherCA, host)},
+		{name: "certificate from known CA", mode: HostKeyStrict, known: []string{caLine}, key: newHostCert(t, hostKey, ca, host)},
+		{name: "certificate for another host", mode: HostKeyAcceptNew, known: []string{caLine}, key: newHostCert(t, hostKey, ca, "other.example.com"), wantErr: "host certificate"},
+		{name: "certificate from unknown CA, accept-new", mode: HostKeyAcceptNew, key: newHostCert(t, hostKey, otherCA, host), recorded: true},
+		{name: "certificate from unknown CA, known key", mode: HostKeyStrict, known: []string{knownLine}, key: newHostCert(t, hostKey, otherCA, host)},
+		{name: "certificate from unknown CA, strict", mode: HostKeyStrict, key: newHostCert(t, hostKey, otherCA, host), wantErr: "unknown host key"},
+		{name: "certificate from unknown CA, changed key", mode: HostKeyAcceptNew, known: []string{knownLine}, key: newHostCert(t, otherKey, otherCA, host), wantErr: "host key mismatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("HOME", dir)
+			file := filepath.Join(dir, "known_hosts")
+			if len(tt.known) > 0 {
+				if err := os.WriteFile(file, []byte(strings.Join(tt.known, "\n")+"\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			callback, err := hostKeyCallback(Options{KnownHosts: file, HostKey: tt.pinned, HostKeyCheck: tt.mode})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = callback(hostname, remote, tt.key)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("expected the key to be accepted: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+
+			data, _ := os.ReadFile(file)
+			if got := len(data) > 0 && len(tt.known) == 0; got != tt.recorded {
+				t.Errorf("recorded = %v, want %v (known_hosts: %q)", got, tt.recorded, data)
+			}
+			if !tt.recorded {
+				return
+			}
+			// 기록된 키(인증서면 안의 키)로 다음 접속은 strict에서도 통과
+			strict, err := hostKeyCallback(Options{KnownHosts: file})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := strict(hostname, remote, hostKey); err != nil {
+				t.Errorf("recorded key rejected: %v", err)
+			}
+		})
+	}
+}
+
+func TestHostKeyCallbackInvalidMode(t *testing.T) {
+	if _, err := hostKeyCallback(Options{HostKeyCheck: "yes"}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
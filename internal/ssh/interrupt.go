@@ -0,0 +1,121 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrInterrupted is returned for sessions refused after Interrupt
+var ErrInterrupted = errors.New("interrupted")
+
+// 원격 프로세스가 SIGINT 후 정리할 시간
+const interruptGrace = 2 * time.Second
+
+// newSession opens a session that Interrupt can reach
+func (c *Client) newSession() (*ssh.Session, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.interrupted {
+		return nil, ErrInterrupted
+	}
+
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	c.sessions[session] = struct{}{}
+	c.touch()
+	return session, nil
+}
+
+func (c *Client) closeSession(session *ssh.Session) {
+	c.mu.Lock()
+	delete(c.sessions, session)
+	c.mu.Unlock()
+	session.Close()
+	c.touch()
+}
+
+// Interrupt sends SIGINT to every running remote command, then SIGTERM and
+// closes the sessions after a short grace period. No new sessions are opened afterwards.
+func (c *Client) Interrupt() {
+	c.mu.Lock()
+	c.interrupted = true
+	sessions := make([]*ssh.Session, 0, len(c.sessions))
+	for session := range c.sessions {
+		sessions = append(sessions, session)
+	}
+	c.mu.Unlock()
+
+	// 진행 중인 SFTP 전송도 중단
+	c.closeTransport()
+
+	if len(sessions) == 0 {
+		return
+	}
+
+	for _, session := range sessions {
+		session.Signal(ssh.SIGINT)
+	}
+
+	// 세션이 스스로 끝나길 잠시 기다림
+	deadline := time.Now().Add(interruptGrace)
+	for time.Now().Before(deadline) && c.activeSessions() > 0 {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	for _, session := range sessions {
+		session.Signal(ssh.SIGTERM)
+		session.Close()
+	}
+}
+
+func (c *Client) activeSessions() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sessions)
+}
+
+// trackTemp records a remote temporary file to remove in Cleanup
+func (c *Client) trackTemp(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tempFiles[path] = struct{}{}
+}
+
+func (c *Client) untrackTemp(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tempFiles, path)
+}
+
+// Cleanup removes remote temporary files left by interrupted or failed uploads
+func (c *Client) Cleanup() error {
+	c.mu.Lock()
+	var paths []string
+	for path := range c.tempFiles {
+		paths = append(paths, path)
+	}
+	c.tempFiles = make(map[string]struct{})
+	c.mu.Unlock()
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	// Interrupt 이후에도 정리는 해야 하므로 직접 세션을 엶
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdout = io.Discard
+	session.Stderr = io.Discard
+	return session.Run(fmt.Sprintf("rm -rf %s", shellQuoteAll(paths)))
+}
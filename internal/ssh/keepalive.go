@@ -0,0 +1,84 @@
+package ssh
+
+import (
+	"time"
+)
+
+// 기본 keepalive 무응답 허용 횟수 (OpenSSH ServerAliveCountMax와 동일)
+const defaultKeepaliveCountMax = 3
+
+// 이보다 오래 쓰이지 않은 연결만 Alive가 서버 왕복으로 확인
+const aliveIdle = 30 * time.Second
+
+// keepalive sends keepalive@openssh.com requests every interval and closes
+// the connection after countMax unanswered requests, so a NAT-dropped link
+// is detected instead of hanging the next step.
+func (c *Client) keepalive(interval time.Duration, countMax int) {
+	if countMax <= 0 {
+		countMax = defaultKeepaliveCountMax
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		if c.ping(interval) {
+			missed = 0
+			continue
+		}
+
+		missed++
+		if missed >= countMax {
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// ping sends one keepalive request and waits up to timeout for the reply
+func (c *Client) ping(timeout time.Duration) bool {
+	reply := make(chan error, 1)
+	go func() {
+		// 서버가 요청을 모르더라도 응답(실패)은 오므로 err만 확인
+		_, _, err := c.conn.SendRequest("keepalive@openssh.com", true, nil)
+		reply <- err
+	}()
+
+	select {
+	case err := <-reply:
+		if err != nil {
+			return false
+		}
+		c.touch()
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// touch records that the connection was just used
+func (c *Client) touch() {
+	c.used.Store(time.Now().UnixNano())
+}
+
+// Alive reports whether the connection is still usable. A closed connection
+// (including one dropped by keepalive) is reported at once; a server round
+// trip is only made when nothing used the connection for aliveIdle.
+func (c *Client) Alive() bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+	}
+	if time.Since(time.Unix(0, c.used.Load())) < aliveIdle {
+		return true
+	}
+	return c.ping(10 * time.Second)
+}
@@ -0,0 +1,112 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxDelete is the safety cap on remote deletions per sync step
+const DefaultMaxDelete = 100
+
+// SyncResult summarizes a sync upload
+type SyncResult struct {
+	Uploaded int
+	Deleted  []string // Remote paths removed (or, in a dry run, that would be removed)
+	DryRun   bool
+}
+
+// deleteStale removes entries of the scanned remote tree that are not in local
+// (excluded paths were already left out of the scan). It refuses to delete
+// more than MaxDelete files, and only lists them in a dry run. Directories
+// that still hold excluded files are kept and not reported.
+func (c *Client) deleteStale(remoteDir string, remote *remoteTree, local map[string]bool, opts UploadOptions) ([]string, error) {
+	var staleFiles, staleDirs []string
+	for f := range remote.files {
+		if !local[f] {
+			staleFiles = append(staleFiles, f)
+		}
+	}
+	for f := range remote.links {
+		if !local[f] {
+			staleFiles = append(staleFiles, f)
+		}
+	}
+	for _, f := range remote.others {
+		if !local[f] {
+			staleFiles = append(staleFiles, f)
+		}
+	}
+	for d := range remote.dirs {
+		if !local[d] {
+			staleDirs = append(staleDirs, d)
+		}
+	}
+	sort.Strings(staleFiles)
+	sort.Strings(staleDirs)
+
+	maxDelete := opts.MaxDelete
+	if maxDelete <= 0 {
+		maxDelete = DefaultMaxDelete
+	}
+	if !opts.DryRun && len(staleFiles) > maxDelete {
+		return nil, fmt.Errorf("refusing to delete %d remote files (max_delete: %d); review them with delete: dry-run or raise max_delete", len(staleFiles), maxDelete)
+	}
+
+	deleted := append([]string{}, staleFiles...)
+	if opts.DryRun {
+		for _, d := range staleDirs {
+			deleted = append(deleted, d+"/")
+		}
+		return deleted, nil
+	}
+
+	// 파일 먼저, 그다음 깊은 디렉토리부터
+	// (제외된 파일이 남아 비어 있지 않은 디렉토리는 그대로 둠)
+	if _, err := c.removeRemote(remoteDir, "rm -f --", staleFiles); err != nil {
+		return nil, err
+	}
+	sort.Slice(staleDirs, func(i, j int) bool {
+		return strings.Count(staleDirs[i], "/") > strings.Count(staleDirs[j], "/")
+	})
+	// 실제로 지워진 디렉토리만 NUL로 구분해 출력
+	removed, err := c.removeRemote(remoteDir, `sh -c 'for d do if rmdir -- "$d" 2>/dev/null; then printf "%s\0" "$d"; fi; done' sh`, staleDirs)
+	if err != nil {
+		return nil, err
+	}
+	var removedDirs []string
+	for _, d := range strings.Split(string(removed), "\x00") {
+		if d != "" {
+			removedDirs = append(removedDirs, d+"/")
+		}
+	}
+	sort.Strings(removedDirs)
+	deleted = append(deleted, removedDirs...)
+
+	return deleted, nil
+}
+
+// removeRemote runs command on the NUL-separated paths fed through stdin,
+// so file names never pass through the shell, and returns its stdout
+func (c *Client) removeRemote(remoteDir, command string, paths []string) ([]byte, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	session, err := c.newSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	defer c.closeSession(session)
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	if err := session.Run(fmt.Sprintf("cd %s && xargs -0 %s", shellQuote(remoteDir), command)); err != nil {
+		return nil, fmt.Errorf("failed to delete remote files: %w (stderr: %s)", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
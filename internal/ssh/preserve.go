@@ -0,0 +1,135 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Preserve selects which file metadata sync and scp uploads carry over
+type Preserve struct {
+	Mode  bool // Permission bits (otherwise files are written 0644)
+	Mtime bool // Modification times
+	Links bool // Recreate symlinks (otherwise they are followed)
+}
+
+// DefaultPreserve keeps permission bits, but not mtimes. Symlinks are
+// followed unless links is asked for, as uploads always did.
+var DefaultPreserve = Preserve{Mode: true}
+
+// ParsePreserve parses a comma-separated list of mode, mtime, links,
+// or "all" / "none". An empty string returns DefaultPreserve.
+func ParsePreserve(s string) (Preserve, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return DefaultPreserve, nil
+	}
+
+	var p Preserve
+	for _, item := range strings.Split(s, ",") {
+		switch strings.TrimSpace(item) {
+		case "all":
+			p = Preserve{Mode: true, Mtime: true, Links: true}
+		case "none":
+		case "mode":
+			p.Mode = true
+		case "mtime":
+			p.Mtime = true
+		case "links":
+			p.Links = true
+		default:
+			return Preserve{}, fmt.Errorf("invalid preserve: %s (expected mode, mtime, links, all or none)", item)
+		}
+	}
+	return p, nil
+}
+
+// UploadOptions controls how sync, tar and scp uploads behave
+type UploadOptions struct {
+	Preserve Preserve
+	Exclude  []string // gitignore-style patterns to skip (after .gorelayignore)
+	Include  []string // Only upload files matching one of these (empty: all)
+
+	Delete    bool // sync: remove remote files missing locally
+	DryRun    bool // sync: only list what Delete would remove
+	MaxDelete int  // sync: refuse to delete more files than this (default: DefaultMaxDelete)
+	Delta     bool // sync: send only changed blocks of large files that exist remotely
+}
+
+// fileAttrs is the metadata written along with a file's content
+type fileAttrs struct {
+	Mode    os.FileMode
+	ModTime time.Time // Zero: leave the remote mtime alone
+}
+
+func (p Preserve) attrs(info os.FileInfo) fileAttrs {
+	attrs := fileAttrs{Mode: 0644}
+	if p.Mode {
+		attrs.Mode = info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	}
+	if p.Mtime {
+		attrs.ModTime = info.ModTime()
+	}
+	return attrs
+}
+
+// getRemoteMode returns the permission bits of a single remote file
+func (c *Client) getRemoteMode(remotePath string) (os.FileMode, bool) {
+	var stdout bytes.Buffer
+	p := shellQuote(remotePath)
+	cmd := fmt.Sprintf("stat -c '%%a %%n' %s 2>/dev/null || stat -f '%%Lp %%N' %s 2>/dev/null", p, p)
+	if err := c.Run(cmd, &stdout, io.Discard); err != nil {
+		return 0, false
+	}
+	for _, mode := range parseModes(stdout.String()) {
+		return mode, true
+	}
+	return 0, false
+}
+
+func parseModes(output string) map[string]os.FileMode {
+	modes := make(map[string]os.FileMode)
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		mode, err := strconv.ParseUint(parts[0], 8, 32)
+		if err != nil {
+			continue
+		}
+		modes[strings.TrimPrefix(parts[1], "./")] = posixToFileMode(uint32(mode))
+	}
+	return modes
+}
+
+// modeMatches reports whether a remote file already has the local
+// permission bits. Always true when modes are not preserved.
+func modeMatches(localPath string, remoteMode os.FileMode, found bool, preserve Preserve) bool {
+	if !preserve.Mode {
+		return true
+	}
+	info, err := os.Stat(localPath)
+	if err != nil || !found {
+		return false
+	}
+	return remoteMode == preserve.attrs(info).Mode
+}
+
+func posixToFileMode(mode uint32) os.FileMode {
+	m := os.FileMode(mode & 0777)
+	if mode&04000 != 0 {
+		m |= os.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		m |= os.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		m |= os.ModeSticky
+	}
+	return m
+}
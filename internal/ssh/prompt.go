@@ -0,0 +1,226 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// Prompter reads secrets such as key passphrases and caches each answer
+// for the rest of the run, so parallel connections ask only once.
+type Prompter struct {
+	mu    sync.Mutex // Guards cache only; never held while reading a secret
+	cache map[string][]byte
+	askMu sync.Mutex // Serializes reading secrets (one prompt at a time)
+}
+
+// 이보다 짧은 비밀은 평범한 출력과 겹치기 쉬워 가리지 않음
+const minRedactLen = 6
+
+// SecretSource tells where a login password comes from.
+// With neither field set, the user is prompted (or askpass is used).
+type SecretSource struct {
+	Env     string // Environment variable holding the secret
+	Command string // Shell command printing the secret to stdout
+}
+
+func NewPrompter() *Prompter {
+	return &Prompter{cache: make(map[string][]byte)}
+}
+
+// Passphrase returns the passphrase for an encrypted key.
+// Sources in order: GORELAY_KEY_PASSPHRASE, askpass command, terminal prompt.
+func (p *Prompter) Passphrase(keyPath string) ([]byte, error) {
+	cacheKey := "passphrase:" + absPath(keyPath)
+	prompt := fmt.Sprintf("Enter passphrase for key '%s': ", keyPath)
+	return p.secret(cacheKey, "GORELAY_KEY_PASSPHRASE", prompt)
+}
+
+// Password returns the login password for user@host:port
+func (p *Prompter) Password(user, host string, port int, source SecretSource) ([]byte, error) {
+	cacheKey := passwordKey(user, host, port)
+	prompt := fmt.Sprintf("%s@%s's password: ", user, host)
+
+	switch {
+	case source.Env != "":
+		if _, ok := os.LookupEnv(source.Env); !ok {
+			return nil, fmt.Errorf("password environment variable %s is not set", source.Env)
+		}
+		return p.secret(cacheKey, source.Env, prompt)
+	case source.Command != "":
+		return p.cached(cacheKey, func() ([]byte, error) {
+			return runSecretCommand(source.Command)
+		})
+	}
+	return p.secret(cacheKey, "", prompt)
+}
+
+// Redact masks every secret read so far, so it never reaches logs.
+// Secrets shorter than minRedactLen are left alone.
+func (p *Prompter) Redact(s string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, value := range p.cache {
+		if len(value) >= minRedactLen {
+			s = strings.ReplaceAll(s, string(value), "****")
+		}
+	}
+	return s
+}
+
+// Forget drops a cached passphrase (e.g. after it turned out to be wrong)
+func (p *Prompter) Forget(keyPath string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.cache, "passphrase:"+absPath(keyPath))
+}
+
+// ForgetPassword drops a cached login password that the server rejected
+func (p *Prompter) ForgetPassword(user, host string, port int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.cache, passwordKey(user, host, port))
+}
+
+// 같은 호스트라도 포트가 다르면 (sshd 여러 개, 포트 포워딩) 다른 계정일 수 있음
+func passwordKey(user, host string, port int) string {
+	return fmt.Sprintf("password:%s@%s:%d", user, host, port)
+}
+
+func (p *Prompter) secret(cacheKey, envName, prompt string) ([]byte, error) {
+	return p.cached(cacheKey, func() ([]byte, error) {
+		return readSecret(envName, prompt)
+	})
+}
+
+// cached returns the cached value for cacheKey, or reads and caches it.
+// Reads are serialized so parallel servers asking at once prompt only once,
+// while Redact keeps working during a prompt.
+func (p *Prompter) cached(cacheKey string, read func() ([]byte, error)) ([]byte, error) {
+	if value, ok := p.lookup(cacheKey); ok {
+		return value, nil
+	}
+
+	p.askMu.Lock()
+	defer p.askMu.Unlock()
+	// 기다리는 동안 다른 쪽이 읽었으면 그 값 사용
+	if value, ok := p.lookup(cacheKey); ok {
+		return value, nil
+	}
+
+	value, err := read()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.cache[cacheKey] = value
+	p.mu.Unlock()
+	return value, nil
+}
+
+func (p *Prompter) lookup(cacheKey string) ([]byte, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	value, ok := p.cache[cacheKey]
+	return value, ok
+}
+
+func readSecret(envName, prompt string) ([]byte, error) {
+	if envName != "" {
+		if value, ok := os.LookupEnv(envName); ok {
+			return []byte(value), nil
+		}
+	}
+
+	if askpass := askpassCommand(); askpass != "" {
+		return runAskpass(askpass, prompt)
+	}
+
+	return readTerminal(prompt)
+}
+
+// askpassCommand follows OpenSSH: SSH_ASKPASS is used when there is no
+// terminal or SSH_ASKPASS_REQUIRE=force. GORELAY_ASKPASS is always used.
+func askpassCommand() string {
+	if cmd := os.Getenv("GORELAY_ASKPASS"); cmd != "" {
+		return cmd
+	}
+
+	cmd := os.Getenv("SSH_ASKPASS")
+	if cmd == "" {
+		return ""
+	}
+
+	switch os.Getenv("SSH_ASKPASS_REQUIRE") {
+	case "force":
+		return cmd
+	case "never":
+		return ""
+	}
+
+	if in, out, err := openTerminal(); err == nil {
+		closeTerminal(in, out)
+		return ""
+	}
+	return cmd
+}
+
+func runAskpass(command, prompt string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(command, prompt)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("askpass command failed: %w (stderr: %s)", err, stderr.String())
+	}
+	return bytes.TrimRight(stdout.Bytes(), "\r\n"), nil
+}
+
+func runSecretCommand(command string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// 출력(비밀번호)은 에러 메시지에 포함하지 않음
+		return nil, fmt.Errorf("password command failed: %w (stderr: %s)", err, stderr.String())
+	}
+	return bytes.TrimRight(stdout.Bytes(), "\r\n"), nil
+}
+
+func readTerminal(prompt string) ([]byte, error) {
+	in, out, err := openTerminal()
+	if err != nil {
+		return nil, fmt.Errorf("no terminal available to prompt (set GORELAY_ASKPASS or SSH_ASKPASS): %w", err)
+	}
+	defer closeTerminal(in, out)
+
+	fmt.Fprint(out, prompt)
+	value, err := term.ReadPassword(int(in.Fd()))
+	fmt.Fprintln(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from terminal: %w", err)
+	}
+	return value, nil
+}
+
+func closeTerminal(in, out *os.File) {
+	in.Close()
+	if out != in {
+		out.Close()
+	}
+}
+
+func absPath(path string) string {
+	path = expandPath(path)
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
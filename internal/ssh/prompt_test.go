@@ -0,0 +1,67 @@
+package ssh
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	p := NewPrompter()
+	p.cache["short"] = []byte("1234")
+	p.cache["long"] = []byte("hunter22")
+
+	got := p.Redact("port 1234 ok, password hunter22")
+	if want := "port 1234 ok, password ****"; got != want {
+		t.Errorf("Redact = %q, want %q", got, want)
+	}
+}
+
+func TestRedactDuringPrompt(t *testing.T) {
+	p := NewPrompter()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go p.cached("password:a@b:22", func() ([]byte, error) {
+		close(started)
+		<-release
+		return []byte("secret-value"), nil
+	})
+	<-started
+
+	redacted := make(chan string)
+	go func() { redacted <- p.Redact("log line") }()
+	select {
+	case <-redacted:
+	case <-time.After(time.Second):
+		t.Fatal("Redact blocked while a prompt was open")
+	}
+	close(release)
+}
+
+func TestCachedReadsOnce(t *testing.T) {
+	p := NewPrompter()
+	var mu sync.Mutex
+	reads := 0
+	read := func() ([]byte, error) {
+		mu.Lock()
+		reads++
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		return []byte("passphrase"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := p.cached("passphrase:/k", read); err != nil || string(value) != "passphrase" {
+				t.Errorf("cached = %q, %v", value, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if reads != 1 {
+		t.Errorf("read %d times, want once", reads)
+	}
+}
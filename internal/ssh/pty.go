@@ -0,0 +1,91 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// RunTTY runs command in a remote PTY attached to the local terminal:
+// raw mode and window size changes are forwarded so interactive programs
+// (sudo prompts, top, less, journalctl -f) behave. SIGINT/SIGTERM reach the
+// remote side through Interrupt.
+func (c *Client) RunTTY(command string, stdout, stderr io.Writer) error {
+	session, err := c.newSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer c.closeSession(session)
+
+	fd := int(os.Stdin.Fd())
+	isTerminal := term.IsTerminal(fd)
+
+	width, height := 80, 24
+	if isTerminal {
+		if w, h, err := term.GetSize(fd); err == nil {
+			width, height = w, h
+		}
+	}
+
+	termType := os.Getenv("TERM")
+	if termType == "" {
+		termType = "xterm-256color"
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty(termType, height, width, modes); err != nil {
+		return fmt.Errorf("failed to request pty: %w", err)
+	}
+
+	// session.Stdin = os.Stdin이면 x/crypto의 복사 고루틴이 명령이 끝난 뒤에도
+	// stdin을 읽고 있다가 다음 입력 (다음 tty 단계, 암호 프롬프트)을 가로챔
+	stdinPipe, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdin: %w", err)
+	}
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	// raw 모드에서는 Ctrl-C가 원격 PTY로 그대로 전달됨
+	if isTerminal {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("failed to set terminal raw mode: %w", err)
+		}
+		defer term.Restore(fd, state)
+	}
+
+	if err := session.Start(command); err != nil {
+		return fmt.Errorf("failed to start command: %w", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	if isTerminal {
+		go watchWindowSize(session, fd, stop)
+	}
+
+	stdin := openStdin()
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		io.Copy(stdinPipe, stdin)
+		stdinPipe.Close()
+	}()
+
+	err = session.Wait()
+
+	// 명령이 끝나면 stdin 읽기를 멈추고 복사가 끝날 때까지 기다림
+	if stdin.Cancel() == nil {
+		<-copied
+	}
+	stdin.Close()
+	return err
+}
@@ -0,0 +1,70 @@
+//go:build !windows
+
+package ssh
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// watchWindowSize forwards local terminal resizes to the remote PTY
+func watchWindowSize(session *ssh.Session, fd int, stop <-chan struct{}) {
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer signal.Stop(resize)
+
+	for {
+		select {
+		case <-resize:
+			if w, h, err := term.GetSize(fd); err == nil {
+				session.WindowChange(h, w)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// stdinReader reads the local stdin through a non-blocking duplicate, so a
+// pending read can be cancelled when the remote command exits
+type stdinReader struct {
+	*os.File
+	pollable bool
+}
+
+func openStdin() *stdinReader {
+	fd, err := syscall.Dup(int(os.Stdin.Fd()))
+	if err != nil {
+		return &stdinReader{File: os.Stdin}
+	}
+	// 논블로킹 fd는 Go 런타임 poller에 등록되어 읽기 마감 시간을 쓸 수 있음
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return &stdinReader{File: os.Stdin}
+	}
+	return &stdinReader{File: os.NewFile(uintptr(fd), "stdin"), pollable: true}
+}
+
+// Cancel makes a pending and every later Read return immediately.
+// It fails when stdin cannot be polled (e.g. the dup failed).
+func (s *stdinReader) Cancel() error {
+	if !s.pollable {
+		return os.ErrNoDeadline
+	}
+	return s.SetReadDeadline(time.Now())
+}
+
+// Close puts stdin back into blocking mode (the flag is shared with the
+// duplicate) and closes the duplicate
+func (s *stdinReader) Close() error {
+	if !s.pollable {
+		return nil
+	}
+	syscall.SetNonblock(int(os.Stdin.Fd()), false)
+	return s.File.Close()
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package ssh
+
+import (
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// watchWindowSize is a no-op: Windows has no SIGWINCH
+func watchWindowSize(session *ssh.Session, fd int, stop <-chan struct{}) {
+	<-stop
+}
+
+// stdinReader reads the console directly: Windows console handles have no
+// read deadline, so a read pending when the command exits is not cancelled
+type stdinReader struct {
+	*os.File
+}
+
+func openStdin() *stdinReader {
+	return &stdinReader{File: os.Stdin}
+}
+
+func (s *stdinReader) Cancel() error {
+	return os.ErrNoDeadline
+}
+
+func (s *stdinReader) Close() error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package ssh
+
+import "strings"
+
+// shellQuote quotes s as a single word for a POSIX shell, so remote paths
+// with spaces, quotes, $ or ; reach commands unchanged. A leading ~ or ~/
+// stays unquoted so the remote shell still expands it to the home directory.
+func shellQuote(s string) string {
+	switch {
+	case s == "~":
+		return s
+	case strings.HasPrefix(s, "~/"):
+		if s == "~/" {
+			return s
+		}
+		return "~/" + quoteWord(s[2:])
+	}
+	return quoteWord(s)
+}
+
+// shellQuoteAll quotes each word and joins them with spaces
+func shellQuoteAll(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = shellQuote(w)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func quoteWord(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/._-+=:,@%", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	// 작은따옴표 안에서는 모든 문자가 그대로, ' 만 '\'' 로 끊어서 넣음
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// parseHashLine splits a sha256sum/shasum output line into hash and name.
+// GNU sha256sum prefixes the line with \ and escapes the name when it
+// contains a backslash or newline.
+func parseHashLine(line string) (hash, name string, ok bool) {
+	escaped := strings.HasPrefix(line, `\`)
+	if escaped {
+		line = line[1:]
+	}
+	if len(line) < 67 || line[64] != ' ' {
+		return "", "", false
+	}
+	hash, name = line[:64], line[66:]
+	if escaped {
+		name = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r").Replace(name)
+	}
+	return hash, name, true
+}
@@ -0,0 +1,300 @@
+package ssh
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hostileNames are file names that break naive shell command construction
+var hostileNames = []string{
+	"a b",
+	"it's",
+	`say "hi"`,
+	"semi;touch PWNED",
+	"$(touch PWNED)",
+	"`touch PWNED`",
+	"line\nbreak",
+	"-rf",
+	"~root",
+	`back\slash`,
+	"star*?[x]",
+	"tab\there",
+	"amp&pipe|lt<gt>",
+}
+
+func requireShell(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+}
+
+// runShell runs script with sh -c in dir and fails the test if any
+// injected command created a PWNED file
+func runShell(t *testing.T, dir, script string) (stdout, stderr string, err error) {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HOME="+dir)
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+	err = cmd.Run()
+	if _, statErr := os.Stat(filepath.Join(dir, "PWNED")); statErr == nil {
+		t.Fatalf("shell injection: %q created PWNED", script)
+	}
+	return outBuf.String(), errBuf.String(), err
+}
+
+func TestQuoteWord(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "''"},
+		{"plain", "plain"},
+		{"/var/www/app-1.2_x+y=z:w,v@host%20", "/var/www/app-1.2_x+y=z:w,v@host%20"},
+		{"-rf", "-rf"},
+		{"a b", "'a b'"},
+		{"it's", `'it'\''s'`},
+		{`say "hi"`, `'say "hi"'`},
+		{"a;b", "'a;b'"},
+		{"$(x)", "'$(x)'"},
+		{"`x`", "'`x`'"},
+		{"a\nb", "'a\nb'"},
+		{"~", "'~'"},
+		{"~/x", "'~/x'"},
+		{"''", `''\'''\'''`},
+	}
+	for _, tt := range tests {
+		if got := quoteWord(tt.in); got != tt.want {
+			t.Errorf("quoteWord(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"~", "~"},
+		{"~/", "~/"},
+		{"~/app", "~/app"},
+		{"~/my app", "~/'my app'"},
+		{"~/it's", `~/'it'\''s'`},
+		{"~root/app", "'~root/app'"},
+		{"/srv/~/app", "'/srv/~/app'"},
+		{"/srv/a b;c", "'/srv/a b;c'"},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuoteReachesShellUnchanged(t *testing.T) {
+	requireShell(t)
+	dir := t.TempDir()
+
+	for _, name := range hostileNames {
+		out, _, err := runShell(t, dir, "printf '%s' "+shellQuote(name))
+		if err != nil || out != name {
+			t.Errorf("printf %s = %q, %v; want %q", shellQuote(name), out, err, name)
+		}
+	}
+
+	// ~/ 뒤는 따옴표 안이어도 홈 디렉토리로 확장됨
+	out, _, err := runShell(t, dir, "printf '%s' "+shellQuote("~/a b;c"))
+	if want := dir + "/a b;c"; err != nil || out != want {
+		t.Errorf("~/ path = %q, %v; want %q", out, err, want)
+	}
+}
+
+func TestShellQuoteAll(t *testing.T) {
+	if got, want := shellQuoteAll([]string{"a", "b c", "~/d e"}), "a 'b c' ~/'d e'"; got != want {
+		t.Errorf("shellQuoteAll = %s, want %s", got, want)
+	}
+	if got := shellQuoteAll(nil); got != "" {
+		t.Errorf("shellQuoteAll(nil) = %q, want empty", got)
+	}
+
+	requireShell(t)
+	out, _, err := runShell(t, t.TempDir(), `printf '%s\0' `+shellQuoteAll(hostileNames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	if strings.Join(got, "|") != strings.Join(hostileNames, "|") {
+		t.Errorf("words = %q, want %q", got, hostileNames)
+	}
+}
+
+func TestParseHashLine(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	tests := []struct {
+		name     string
+		line     string
+		wantName string
+		ok       bool
+	}{
+		{"text mode", hash + "  ./dir/file", "./dir/file", true},
+		{"binary mode", hash + " *./file", "./file", true},
+		{"spaces", hash + "  ./a b  c", "./a b  c", true},
+		{"quote and dollar", hash + "  ./it's $(x)", "./it's $(x)", true},
+		{"escaped newline", `\` + hash + `  ./line\nbreak`, "./line\nbreak", true},
+		{"escaped backslash", `\` + hash + `  ./back\\slash`, `./back\slash`, true},
+		{"escaped both", `\` + hash + `  ./a\\n\nb`, "./a\\n\nb", true},
+		{"unescaped backslash kept", hash + `  ./back\slash`, `./back\slash`, true},
+		{"too short", hash + "  ", "", false},
+		{"no separator", hash + "x ./file", "", false},
+		{"empty", "", "", false},
+		{"other output", "E", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotHash, gotName, ok := parseHashLine(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && (gotHash != hash || gotName != tt.wantName) {
+				t.Errorf("parseHashLine = %q, %q; want %q, %q", gotHash, gotName, hash, tt.wantName)
+			}
+		})
+	}
+}
+
+// TestRemoteHashCommand runs the checksum command verifyRemote sends, with
+// names fed NUL-separated like the real one, and parses its output
+func TestRemoteHashCommand(t *testing.T) {
+	requireShell(t)
+	dir := t.TempDir()
+	work := filepath.Join(dir, "it's a $(dir); x")
+	if err := os.Mkdir(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	want := make(map[string]string)
+	var paths []string
+	for _, name := range hostileNames {
+		if err := os.WriteFile(filepath.Join(work, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte(name))
+		want[name] = hex.EncodeToString(sum[:])
+		paths = append(paths, "./"+name)
+	}
+
+	cmd := exec.Command("sh", "-c", remoteHashCmd+"\ncd "+shellQuote(work)+" && xargs -0 $h")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	// 이스케이프된 이름에는 줄바꿈이 없으므로 줄 단위로 나눌 수 있음
+	for _, line := range strings.Split(string(out), "\n") {
+		if hash, name, ok := parseHashLine(line); ok {
+			got[strings.TrimPrefix(name, "./")] = hash
+		}
+	}
+	for name, hash := range want {
+		if got[name] != hash {
+			t.Errorf("%q: hash %q, want %q", name, got[name], hash)
+		}
+	}
+}
+
+// writeTestTar writes a tar.gz of name → content and returns its SHA256
+func writeTestTar(t *testing.T, archive string, files map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	if err := os.WriteFile(archive, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+func TestTarInstallScriptQuoting(t *testing.T) {
+	requireShell(t)
+	if _, err := exec.LookPath("tar"); err != nil {
+		t.Skip("tar not installed")
+	}
+
+	for _, name := range hostileNames {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			// 디렉토리 대상
+			target := filepath.Join(dir, name)
+			archive := filepath.Join(dir, ".gorelay-1.tar.gz")
+			sum := writeTestTar(t, archive, map[string]string{"./" + name: "dir member"})
+			if _, _, err := runShell(t, dir, tarInstallScript(target, archive, "", sum, "1")); err != nil {
+				t.Fatalf("directory install: %v", err)
+			}
+			if got, _ := os.ReadFile(filepath.Join(target, name)); string(got) != "dir member" {
+				t.Errorf("directory install wrote %q", got)
+			}
+
+			// 단일 파일 대상
+			fileTarget := filepath.Join(dir, "file "+name)
+			sum = writeTestTar(t, archive, map[string]string{name: "file member"})
+			if _, _, err := runShell(t, dir, tarInstallScript(fileTarget, archive, name, sum, "2")); err != nil {
+				t.Fatalf("file install: %v", err)
+			}
+			if got, _ := os.ReadFile(fileTarget); string(got) != "file member" {
+				t.Errorf("file install wrote %q", got)
+			}
+
+			// 임시 파일이 남지 않아야 함
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 2 {
+				t.Errorf("leftover files: %v", entries)
+			}
+		})
+	}
+}
+
+func TestTarInstallScriptChecksumMismatch(t *testing.T) {
+	requireShell(t)
+	dir := t.TempDir()
+	target := filepath.Join(dir, "app")
+	if err := os.MkdirAll(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(target, "old"), []byte("old"), 0644)
+
+	archive := filepath.Join(dir, ".gorelay-1.tar.gz")
+	writeTestTar(t, archive, map[string]string{"./new": "new"})
+	_, stderr, err := runShell(t, dir, tarInstallScript(target, archive, "", strings.Repeat("0", 64), "1"))
+	if err == nil || !strings.Contains(stderr, "archive checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v (stderr: %s)", err, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(target, "new")); !os.IsNotExist(err) {
+		t.Error("archive was extracted despite the mismatch")
+	}
+	if _, err := os.Stat(archive); !os.IsNotExist(err) {
+		t.Error("rejected archive was not removed")
+	}
+}
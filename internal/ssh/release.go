@@ -0,0 +1,279 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultKeepReleases is how many releases a release step keeps by default
+const DefaultKeepReleases = 5
+
+// Deployed release names are appended to this file in the base directory,
+// which gives the release order (git SHAs do not sort by time)
+const releaseLog = "releases.log"
+
+// ReleaseOptions controls a release deployment
+type ReleaseOptions struct {
+	Upload UploadOptions
+	Name   string   // Directory name under releases/
+	Shared []string // Paths linked from shared/ into every release
+	Keep   int      // Releases to keep (default: DefaultKeepReleases)
+}
+
+// Release is one directory under <base>/releases
+type Release struct {
+	Name    string
+	Current bool
+	Logged  bool // Listed in releases.log (a finished deployment)
+}
+
+// ReleaseResult summarizes a release deployment
+type ReleaseResult struct {
+	Name    string
+	Removed []string // Old releases deleted by retention
+}
+
+// DeployRelease uploads localPath into <base>/releases/<name>, links the
+// shared paths, switches <base>/current to it in one rename and removes
+// the oldest releases beyond opts.Keep. The live release is never touched
+// until the switch, so a failed upload leaves the running version as is.
+func (c *Client) DeployRelease(localPath, baseDir string, opts ReleaseOptions) (ReleaseResult, error) {
+	result := ReleaseResult{Name: opts.Name}
+	if err := validReleaseName(opts.Name); err != nil {
+		return result, err
+	}
+
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to stat local path: %w", err)
+	}
+
+	// 같은 이름 (같은 커밋 재배포)이 있으면 시각을 붙임
+	var stdout bytes.Buffer
+	base := shellQuote(baseDir)
+	check := fmt.Sprintf("mkdir -p %s/releases %s/shared && if [ -e %s/releases/%s ]; then echo exists; fi", base, base, base, shellQuote(opts.Name))
+	if err := c.Run(check, &stdout, io.Discard); err != nil {
+		return result, fmt.Errorf("failed to prepare %s: %w", baseDir, err)
+	}
+	if strings.TrimSpace(stdout.String()) == "exists" {
+		result.Name = opts.Name + "-" + time.Now().UTC().Format("20060102150405")
+	}
+
+	releaseDir := path.Join(baseDir, "releases", result.Name)
+	if c.verbose {
+		fmt.Printf("      Release: %s\n", releaseDir)
+	}
+
+	// 전환 전에 중단되면 반쯤 올라간 릴리스를 정리
+	c.trackTemp(releaseDir)
+	if stat.IsDir() {
+		err = c.uploadDirTar(localPath, releaseDir, opts.Upload)
+	} else {
+		err = c.uploadFileTar(localPath, path.Join(releaseDir, filepath.Base(localPath)), opts.Upload)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if err := c.linkShared(baseDir, releaseDir, opts.Shared); err != nil {
+		return result, err
+	}
+	if err := c.SwitchRelease(baseDir, result.Name); err != nil {
+		return result, err
+	}
+	c.untrackTemp(releaseDir)
+
+	logCmd := fmt.Sprintf("echo %s >> %s", shellQuote(result.Name), shellQuote(path.Join(baseDir, releaseLog)))
+	if err := c.Run(logCmd, io.Discard, io.Discard); err != nil {
+		return result, fmt.Errorf("failed to record release: %w", err)
+	}
+
+	result.Removed, err = c.pruneReleases(baseDir, opts.Keep)
+	return result, err
+}
+
+// linkShared replaces each shared path in the release with a relative
+// symlink into <base>/shared. On first use the release's own copy (if any)
+// seeds the shared one, otherwise an empty directory is created.
+func (c *Client) linkShared(baseDir, releaseDir string, shared []string) error {
+	if len(shared) == 0 {
+		return nil
+	}
+
+	var script strings.Builder
+	script.WriteString("set -e\n")
+	for _, s := range shared {
+		s = strings.Trim(path.Clean(s), "/")
+		if s == "" || s == "." || s == ".." || strings.HasPrefix(s, "../") {
+			return fmt.Errorf("invalid shared path: %s", s)
+		}
+
+		// releases/<name>/a/b → ../../../shared/a/b
+		target := strings.Repeat("../", strings.Count(s, "/")+2) + "shared/" + s
+		relPath := path.Join(releaseDir, s)
+		sharedPath := path.Join(baseDir, "shared", s)
+		rel, sh := shellQuote(relPath), shellQuote(sharedPath)
+		fmt.Fprintf(&script, "mkdir -p %s %s\n", shellQuote(path.Dir(relPath)), shellQuote(path.Dir(sharedPath)))
+		fmt.Fprintf(&script, "if [ ! -e %s ]; then if [ -e %s ]; then mv %s %s; else mkdir -p %s; fi; fi\n", sh, rel, rel, sh, sh)
+		fmt.Fprintf(&script, "rm -rf %s && ln -s %s %s\n", rel, quoteWord(target), rel)
+
+		if c.verbose {
+			fmt.Printf("      Shared: %s\n", s)
+		}
+	}
+
+	var stderr bytes.Buffer
+	if err := c.Run(script.String(), io.Discard, &stderr); err != nil {
+		return fmt.Errorf("failed to link shared paths: %w (stderr: %s)", err, stderr.String())
+	}
+	return nil
+}
+
+// SwitchRelease points <base>/current at releases/<name>. The new link is
+// created beside the old one and renamed over it, so current always
+// resolves to either the old or the new release.
+func (c *Client) SwitchRelease(baseDir, name string) error {
+	if err := validReleaseName(name); err != nil {
+		return err
+	}
+
+	tmp := ".current-" + randomID()
+	script := strings.Join([]string{
+		fmt.Sprintf("cd %s || exit 1", shellQuote(baseDir)),
+		fmt.Sprintf("r=%s; t=%s", shellQuote("releases/"+name), tmp),
+		`[ -d "$r" ] || { echo "release not found: $r" >&2; exit 1; }`,
+		`if [ -e current ] && [ ! -L current ]; then echo "current exists and is not a symlink" >&2; exit 1; fi`,
+		`ln -s "$r" "$t"`,
+		// GNU mv -T, BSD/macOS mv -h: 링크가 가리키는 디렉토리 안으로 옮기지 않고 교체
+		`mv -Tf "$t" current 2>/dev/null || mv -hf "$t" current || { rm -f "$t"; exit 1; }`,
+	}, "\n")
+
+	var stderr bytes.Buffer
+	if err := c.Run(script, io.Discard, &stderr); err != nil {
+		return fmt.Errorf("failed to switch current to %s: %w (stderr: %s)", name, err, stderr.String())
+	}
+	if c.verbose {
+		fmt.Printf("      current → releases/%s\n", name)
+	}
+	return nil
+}
+
+// Releases lists the releases under <base>/releases, oldest first
+func (c *Client) Releases(baseDir string) ([]Release, error) {
+	script := fmt.Sprintf("cd %s || exit 1; echo \"C $(readlink current 2>/dev/null)\"; cat %s 2>/dev/null | sed 's/^/L /'; for d in releases/*/; do [ -d \"$d\" ] && echo \"D $(basename \"$d\")\"; done; exit 0", shellQuote(baseDir), releaseLog)
+
+	var stdout, stderr bytes.Buffer
+	if err := c.Run(script, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("failed to list releases in %s: %w (stderr: %s)", baseDir, err, stderr.String())
+	}
+	return parseReleases(stdout.String()), nil
+}
+
+// parseReleases orders release directories by releases.log. Directories
+// missing from the log (manual copies, interrupted uploads) count as oldest.
+func parseReleases(output string) []Release {
+	var current string
+	var logged, dirs []string
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		value := strings.TrimSpace(line[2:])
+		switch line[0] {
+		case 'C':
+			current = path.Base(value)
+		case 'L':
+			logged = append(logged, value)
+		case 'D':
+			dirs = append(dirs, value)
+		}
+	}
+
+	exists := make(map[string]bool, len(dirs))
+	for _, d := range dirs {
+		exists[d] = true
+	}
+
+	// 마지막 배포 위치 기준 (재배포된 이름은 뒤로)
+	position := make(map[string]int)
+	for i, name := range logged {
+		if exists[name] {
+			position[name] = i + 1
+		}
+	}
+	sort.SliceStable(dirs, func(i, j int) bool {
+		if position[dirs[i]] != position[dirs[j]] {
+			return position[dirs[i]] < position[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	releases := make([]Release, len(dirs))
+	for i, d := range dirs {
+		releases[i] = Release{Name: d, Current: d == current, Logged: position[d] > 0}
+	}
+	return releases
+}
+
+// pruneReleases removes the oldest releases so that keep remain.
+// The current release is always kept.
+func (c *Client) pruneReleases(baseDir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		keep = DefaultKeepReleases
+	}
+
+	releases, err := c.Releases(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := staleReleases(releases, keep)
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := fmt.Sprintf("cd %s && rm -rf -- %s", shellQuote(path.Join(baseDir, "releases")), shellQuoteAll(removed))
+	if err := c.Run(cmd, io.Discard, &stderr); err != nil {
+		return nil, fmt.Errorf("failed to remove old releases: %w (stderr: %s)", err, stderr.String())
+	}
+	return removed, nil
+}
+
+// staleReleases picks the releases beyond the newest keep. Only releases in
+// releases.log count: other directories may be uploads still in progress
+// (another run deploying to the same server) and are never removed.
+func staleReleases(releases []Release, keep int) []string {
+	var logged []Release
+	for _, r := range releases {
+		if r.Logged {
+			logged = append(logged, r)
+		}
+	}
+
+	var stale []string
+	for i, r := range logged {
+		if len(logged)-i <= keep {
+			break
+		}
+		if !r.Current {
+			stale = append(stale, r.Name)
+		}
+	}
+	return stale
+}
+
+// validReleaseName rejects names that could point outside releases/
+func validReleaseName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/ \t\n'\"$`\\;&|<>*?") {
+		return fmt.Errorf("invalid release name: %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package ssh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReleases(t *testing.T) {
+	output := "C releases/b\n" +
+		"L a\nL b\nL gone\nL c\nL a\n" +
+		"D a\nD b\nD c\nD manual\nD uploading\n"
+
+	want := []Release{
+		{Name: "manual"},
+		{Name: "uploading"},
+		{Name: "b", Current: true, Logged: true},
+		{Name: "c", Logged: true},
+		{Name: "a", Logged: true}, // 재배포된 이름은 마지막 위치 기준
+	}
+	if got := parseReleases(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseReleases =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestStaleReleases(t *testing.T) {
+	release := func(name string, current, logged bool) Release {
+		return Release{Name: name, Current: current, Logged: logged}
+	}
+
+	tests := []struct {
+		name     string
+		releases []Release
+		keep     int
+		want     []string
+	}{
+		{
+			name:     "within keep",
+			releases: []Release{release("a", false, true), release("b", true, true)},
+			keep:     2,
+		},
+		{
+			name:     "oldest first",
+			releases: []Release{release("a", false, true), release("b", false, true), release("c", false, true), release("d", true, true)},
+			keep:     2,
+			want:     []string{"a", "b"},
+		},
+		{
+			name:     "current kept after a rollback",
+			releases: []Release{release("a", false, true), release("b", true, true), release("c", false, true), release("d", false, true)},
+			keep:     2,
+			want:     []string{"a"},
+		},
+		{
+			// 다른 실행이 올리는 중인 디렉토리는 log에 없음
+			name:     "unlogged directories are never removed",
+			releases: []Release{release("uploading", false, false), release("manual", false, false), release("a", false, true), release("b", true, true)},
+			keep:     1,
+			want:     []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := staleReleases(tt.releases, tt.keep); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("staleReleases = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
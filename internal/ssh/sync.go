@@ -0,0 +1,613 @@
+package ssh
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yejune/gorelay/internal/ignore"
+	"github.com/yejune/gorelay/internal/sftp"
+)
+
+// SFTP로 동시에 올릴 파일 수 (요청은 한 세션에서 파이프라인으로 처리)
+const sftpUploadWorkers = 16
+
+// 원격 sha256 명령 선택 (coreutils 또는 BSD/macOS shasum)
+const remoteHashCmd = `h=sha256sum; command -v sha256sum >/dev/null 2>&1 || h="shasum -a 256"`
+
+// localEntry is a file, symlink or directory found by walking the upload root
+type localEntry struct {
+	relPath string // Slash-separated, relative to the root
+	path    string
+	info    os.FileInfo
+	hash    string // Regular files only
+}
+
+// remoteTree is what one scan of the remote directory returns
+type remoteTree struct {
+	exists bool
+	hashes map[string]string
+	modes  map[string]os.FileMode
+	files  map[string]bool   // Regular files, hashed or not
+	links  map[string]string // Symlink → target
+	dirs   map[string]bool
+	others []string // Non-directories that are neither files nor links
+}
+
+// uploadDirSync uploads a directory with checksum comparison (only changed files).
+// Remote hashes come from a single command while local files are hashed in
+// parallel; changed files then travel over one pipelined SFTP session or one
+// tar stream, and their remote hashes are verified in one more command.
+func (c *Client) uploadDirSync(localDir, remoteDir string, opts UploadOptions) (SyncResult, error) {
+	result := SyncResult{DryRun: opts.DryRun}
+
+	matcher, err := ignore.New(localDir, opts.Exclude, opts.Include)
+	if err != nil {
+		return result, err
+	}
+
+	entries, err := c.walkLocal(localDir, matcher, opts.Preserve)
+	if err != nil {
+		return result, err
+	}
+
+	// 원격 스캔과 로컬 해시 계산을 동시에
+	var remote *remoteTree
+	var remoteErr error
+	scanned := make(chan struct{})
+	go func() {
+		remote, remoteErr = c.scanRemote(remoteDir, matcher, opts.Preserve.Mode)
+		close(scanned)
+	}()
+	hashErr := hashLocal(entries)
+	<-scanned
+	if hashErr != nil {
+		return result, hashErr
+	}
+	if remoteErr != nil {
+		return result, remoteErr
+	}
+	if !remote.exists && c.verbose {
+		fmt.Printf("      No existing files on remote (new directory)\n")
+	}
+
+	var changed, missingDirs []*localEntry
+	skipped := 0
+	for _, e := range entries {
+		switch {
+		case e.info.IsDir():
+			if !remote.dirs[e.relPath] {
+				missingDirs = append(missingDirs, e)
+			}
+		case e.info.Mode()&os.ModeSymlink != 0:
+			target, _ := os.Readlink(e.path)
+			if current, ok := remote.links[e.relPath]; ok && current == filepath.ToSlash(target) {
+				skipped++
+				continue
+			}
+			changed = append(changed, e)
+		default:
+			remoteMode, hasMode := remote.modes[e.relPath]
+			if remote.hashes[e.relPath] == e.hash && modeMatches(e.path, remoteMode, hasMode, opts.Preserve) {
+				skipped++
+				if c.verbose {
+					fmt.Printf("      Skip (unchanged): %s\n", e.relPath)
+				}
+				continue
+			}
+			changed = append(changed, e)
+		}
+	}
+
+	if c.verbose {
+		for _, e := range changed {
+			fmt.Printf("      Upload: %s\n", e.relPath)
+		}
+	}
+
+	// 델타 모드: 원격에 이미 있는 큰 파일은 바뀐 블록만 전송
+	batch := changed
+	var deltas []*localEntry
+	if opts.Delta {
+		batch = nil
+		for _, e := range changed {
+			if _, ok := remote.hashes[e.relPath]; ok && e.info.Mode().IsRegular() && e.info.Size() >= deltaMinSize {
+				deltas = append(deltas, e)
+			} else {
+				batch = append(batch, e)
+			}
+		}
+	}
+
+	if len(changed) > 0 || len(missingDirs) > 0 || !remote.exists {
+		if len(batch) > 0 || len(missingDirs) > 0 || !remote.exists {
+			if err := c.sendBatch(remoteDir, missingDirs, batch, opts.Preserve); err != nil {
+				return result, err
+			}
+		}
+		for _, e := range deltas {
+			if err := c.deltaOrFull(e.path, path.Join(remoteDir, e.relPath), e.hash, opts.Preserve); err != nil {
+				return result, err
+			}
+		}
+		if err := c.verifyRemote(remoteDir, changed); err != nil {
+			return result, err
+		}
+	}
+
+	result.Uploaded = len(changed)
+	if c.verbose {
+		fmt.Printf("      Uploaded: %d, Skipped: %d\n", result.Uploaded, skipped)
+	}
+	if !opts.Delete {
+		return result, nil
+	}
+
+	// 미러 모드: 로컬에 없는 원격 파일 삭제
+	local := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		local[e.relPath] = true
+	}
+	result.Deleted, err = c.deleteStale(remoteDir, remote, local, opts)
+	return result, err
+}
+
+// walkLocal collects the entries to sync under localDir. Symlinks are
+// resolved here when links are not preserved.
+func (c *Client) walkLocal(localDir string, matcher *ignore.Matcher, preserve Preserve) ([]*localEntry, error) {
+	var entries []*localEntry
+	err := filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, _ := filepath.Rel(localDir, path)
+		if relPath == "." {
+			return nil
+		}
+		if skip, err := c.skipExcluded(matcher, relPath, info); skip {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 && !preserve.Links {
+			// 링크를 따라감 (디렉토리 링크는 건너뜀)
+			target, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if target.IsDir() {
+				if c.verbose {
+					fmt.Printf("      Skip (symlink to directory): %s\n", relPath)
+				}
+				return nil
+			}
+			info = target
+		}
+
+		if info.IsDir() || info.Mode().IsRegular() || info.Mode()&os.ModeSymlink != 0 {
+			entries = append(entries, &localEntry{relPath: filepath.ToSlash(relPath), path: path, info: info})
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// hashLocal computes SHA256 of regular files using all CPUs
+func hashLocal(entries []*localEntry) error {
+	work := make(chan *localEntry)
+	errs := make(chan error, 1)
+	var wg sync.WaitGroup
+
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range work {
+				hash, err := localChecksum(e.path)
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					continue
+				}
+				e.hash = hash
+			}
+		}()
+	}
+
+	for _, e := range entries {
+		if e.info.Mode().IsRegular() {
+			work <- e
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// scanRemote lists the entries under remoteDir, then hashes (and, with
+// withModes, stats) only the files the matcher keeps. The listing is
+// NUL-separated and names are fed back through stdin, so no file name can
+// fake an entry; both outputs are parsed as they stream in.
+func (c *Client) scanRemote(remoteDir string, matcher *ignore.Matcher, withModes bool) (*remoteTree, error) {
+	scan := newRemoteScan(matcher)
+	if err := c.streamRemote(remoteListScript(remoteDir), nil, scanNUL, scan.listRecord); err != nil {
+		return nil, fmt.Errorf("failed to scan remote directory: %w", err)
+	}
+	if len(scan.hashPaths) == 0 {
+		return scan.tree, nil
+	}
+
+	stdin := strings.NewReader(strings.Join(scan.hashPaths, "\x00") + "\x00")
+	if err := c.streamRemote(remoteHashScript(remoteDir, withModes), stdin, bufio.ScanLines, scan.hashLine); err != nil {
+		return nil, fmt.Errorf("failed to hash remote files: %w", err)
+	}
+	return scan.tree, nil
+}
+
+// remoteListScript prints E when remoteDir exists, then one record per entry:
+// D, F or O followed by the path, or L followed by the path and, as the next
+// record, the link target. Records end with NUL.
+func remoteListScript(remoteDir string) string {
+	return strings.Join([]string{
+		fmt.Sprintf("cd %s 2>/dev/null || exit 0", shellQuote(remoteDir)),
+		`printf 'E\0'`,
+		`find . -mindepth 1 -exec sh -c 'for p; do if [ -L "$p" ]; then printf "L%s\0%s\0" "$p" "$(readlink "$p")"; elif [ -d "$p" ]; then printf "D%s\0" "$p"; elif [ -f "$p" ]; then printf "F%s\0" "$p"; else printf "O%s\0" "$p"; fi; done' sh {} +`,
+	}, "\n")
+}
+
+// remoteHashScript hashes the NUL-separated paths read from stdin and, with
+// withModes, prints "M <mode> <path>" lines for them
+func remoteHashScript(remoteDir string, withModes bool) string {
+	// 읽을 수 없는 파일이 있어도 계속 (해시가 없으면 다시 업로드됨)
+	inner := `$0 "$@" 2>/dev/null`
+	if withModes {
+		// GNU/busybox stat -c, BSD stat -f
+		inner += `; stat -c "M %a %n" "$@" 2>/dev/null || stat -f "M %Lp %N" "$@" 2>/dev/null`
+	}
+	return fmt.Sprintf("%s\ncd %s && xargs -0 sh -c '%s; exit 0' \"$h\"", remoteHashCmd, shellQuote(remoteDir), inner)
+}
+
+// remoteScan builds a remoteTree from the output of the two scan scripts
+type remoteScan struct {
+	tree      *remoteTree
+	matcher   *ignore.Matcher
+	lastLink  string   // Link whose target is the next record
+	hashPaths []string // Files to hash, as ./path
+}
+
+func newRemoteScan(matcher *ignore.Matcher) *remoteScan {
+	return &remoteScan{
+		matcher: matcher,
+		tree: &remoteTree{
+			hashes: make(map[string]string),
+			modes:  make(map[string]os.FileMode),
+			files:  make(map[string]bool),
+			links:  make(map[string]string),
+			dirs:   make(map[string]bool),
+		},
+	}
+}
+
+func (s *remoteScan) listRecord(record string) {
+	if s.lastLink != "" {
+		if !s.matcher.Excluded(s.lastLink, false) {
+			s.tree.links[s.lastLink] = record
+		}
+		s.lastLink = ""
+		return
+	}
+	if record == "E" {
+		s.tree.exists = true
+		return
+	}
+	if len(record) < 2 {
+		return
+	}
+
+	relPath := strings.TrimPrefix(record[1:], "./")
+	switch record[0] {
+	case 'D':
+		if !s.matcher.Excluded(relPath, true) {
+			s.tree.dirs[relPath] = true
+		}
+	case 'L':
+		s.lastLink = relPath
+	case 'F':
+		if s.matcher.Excluded(relPath, false) {
+			return
+		}
+		s.tree.files[relPath] = true
+		// 줄 단위 해시 출력을 깨뜨리는 이름은 해시하지 않음 (해시가 없으면 다시 업로드)
+		if !strings.ContainsAny(relPath, "\r\n") {
+			s.hashPaths = append(s.hashPaths, "./"+relPath)
+		}
+	case 'O':
+		if !s.matcher.Excluded(relPath, false) {
+			s.tree.others = append(s.tree.others, relPath)
+		}
+	}
+}
+
+// hashLine records a hash or mode line, but only for files that were listed
+func (s *remoteScan) hashLine(line string) {
+	if rest, ok := strings.CutPrefix(line, "M "); ok {
+		mode, name, _ := strings.Cut(rest, " ")
+		relPath := strings.TrimPrefix(name, "./")
+		if m, err := strconv.ParseUint(mode, 8, 32); err == nil && s.tree.files[relPath] {
+			s.tree.modes[relPath] = posixToFileMode(uint32(m))
+		}
+		return
+	}
+	// sha256sum 출력: <64자 해시>  <경로> (바이너리 모드는 ' *')
+	if hash, name, ok := parseHashLine(line); ok {
+		if relPath := strings.TrimPrefix(name, "./"); s.tree.files[relPath] {
+			s.tree.hashes[relPath] = hash
+		}
+	}
+}
+
+// streamRemote runs command, feeding it stdin if set, and calls fn for each
+// record of its output as split by split
+func (c *Client) streamRemote(command string, stdin io.Reader, split bufio.SplitFunc, fn func(string)) error {
+	session, err := c.newSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer c.closeSession(session)
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	session.Stdin = stdin
+
+	if err := session.Start(command); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	scanner.Split(split)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		// 남은 출력을 버려서 원격 명령이 막히지 않게 함
+		io.Copy(io.Discard, stdout)
+		session.Wait()
+		return err
+	}
+	if err := session.Wait(); err != nil {
+		return fmt.Errorf("%w (stderr: %s)", err, stderr.String())
+	}
+	return nil
+}
+
+// scanNUL is a bufio.SplitFunc for NUL-terminated records
+func scanNUL(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// sendBatch ships directories, files and symlinks in one go: a pipelined
+// SFTP session when available, otherwise a single tar stream
+func (c *Client) sendBatch(remoteDir string, dirs, files []*localEntry, preserve Preserve) error {
+	t, err := c.transport()
+	if err != nil {
+		return err
+	}
+
+	if sftpT, ok := t.(*sftpTransport); ok {
+		return c.sendBatchSFTP(sftpT, remoteDir, dirs, files, preserve)
+	}
+	return c.sendBatchTar(remoteDir, dirs, files, preserve)
+}
+
+func (c *Client) sendBatchSFTP(t *sftpTransport, remoteDir string, dirs, files []*localEntry, preserve Preserve) error {
+	// 필요한 디렉토리 먼저 (중복 제거, 상위부터)
+	needed := map[string]bool{remoteDir: true}
+	for _, e := range dirs {
+		needed[path.Join(remoteDir, e.relPath)] = true
+	}
+	for _, e := range files {
+		needed[path.Dir(path.Join(remoteDir, e.relPath))] = true
+	}
+	var sorted []string
+	for dir := range needed {
+		sorted = append(sorted, dir)
+	}
+	sort.Strings(sorted)
+	for _, dir := range sorted {
+		if err := t.mkdirAll(dir); err != nil {
+			return err
+		}
+	}
+
+	work := make(chan *localEntry)
+	errs := make(chan error, len(files))
+	var wg sync.WaitGroup
+
+	for i := 0; i < sftpUploadWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range work {
+				if err := sendEntry(t, e, path.Join(remoteDir, e.relPath), preserve); err != nil {
+					errs <- fmt.Errorf("%s: %w", e.relPath, err)
+				}
+			}
+		}()
+	}
+
+	for _, e := range files {
+		work <- e
+	}
+	close(work)
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
+// sendEntry writes one file or symlink whose parent directory already exists
+func sendEntry(t transport, e *localEntry, remotePath string, preserve Preserve) error {
+	if e.info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(e.path)
+		if err != nil {
+			return fmt.Errorf("failed to read symlink: %w", err)
+		}
+		_, err = t.symlink(filepath.ToSlash(target), remotePath)
+		return err
+	}
+
+	file, err := os.Open(e.path)
+	if err != nil {
+		return fmt.Errorf("failed to read local file: %w", err)
+	}
+	defer file.Close()
+	return t.writeFile(remotePath, file, e.info.Size(), preserve.attrs(e.info))
+}
+
+// sendBatchTar streams a tar.gz of the changed entries into tar -x on the remote
+func (c *Client) sendBatchTar(remoteDir string, dirs, files []*localEntry, preserve Preserve) error {
+	session, err := c.newSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer c.closeSession(session)
+
+	pipeReader, pipeWriter := io.Pipe()
+	session.Stdin = pipeReader
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+
+	go func() {
+		gzWriter := gzip.NewWriter(pipeWriter)
+		tarWriter := tar.NewWriter(gzWriter)
+		err := writeTarEntries(tarWriter, append(dirs, files...), preserve)
+		if err == nil {
+			err = tarWriter.Close()
+		}
+		if err == nil {
+			err = gzWriter.Close()
+		}
+		pipeWriter.CloseWithError(err)
+	}()
+
+	// -m: 수정 시간을 보존하지 않으면 업로드 시각으로
+	flags := "-xzf"
+	if !preserve.Mtime {
+		flags = "-mxzf"
+	}
+	dir := shellQuote(remoteDir)
+	cmd := fmt.Sprintf("mkdir -p %s && tar %s - --no-same-owner -C %s", dir, flags, dir)
+	err = session.Run(cmd)
+	pipeReader.CloseWithError(err)
+	if err != nil {
+		return fmt.Errorf("failed to extract files: %w (stderr: %s)", err, stderr.String())
+	}
+	return nil
+}
+
+func writeTarEntries(tarWriter *tar.Writer, entries []*localEntry, preserve Preserve) error {
+	for _, e := range entries {
+		var link string
+		if e.info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(e.path)
+			if err != nil {
+				return err
+			}
+			link = filepath.ToSlash(target)
+		}
+
+		header, err := tar.FileInfoHeader(e.info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = e.relPath
+		header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+		if e.info.Mode().IsRegular() {
+			header.Mode = int64(sftp.PosixMode(preserve.attrs(e.info).Mode))
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if e.info.Mode().IsRegular() {
+			if err := copyFile(tarWriter, e.path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// verifyRemote re-hashes the uploaded regular files in one command
+func (c *Client) verifyRemote(remoteDir string, files []*localEntry) error {
+	var paths []string
+	expected := make(map[string]string)
+	for _, e := range files {
+		if e.info.Mode().IsRegular() {
+			paths = append(paths, "./"+e.relPath)
+			expected[e.relPath] = e.hash
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	session, err := c.newSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer c.closeSession(session)
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	cmd := fmt.Sprintf("%s\ncd %s && xargs -0 $h", remoteHashCmd, shellQuote(remoteDir))
+	if err := session.Run(cmd); err != nil {
+		return fmt.Errorf("failed to verify remote checksums: %w (stderr: %s)", err, stderr.String())
+	}
+
+	actual := make(map[string]string)
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if hash, name, ok := parseHashLine(line); ok {
+			actual[strings.TrimPrefix(name, "./")] = hash
+		}
+	}
+	for relPath, hash := range expected {
+		if actual[relPath] != hash {
+			return fmt.Errorf("checksum mismatch for %s: local=%s, remote=%s", relPath, hash, actual[relPath])
+		}
+	}
+	return nil
+}
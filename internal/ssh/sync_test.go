@@ -0,0 +1,112 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yejune/gorelay/internal/ignore"
+)
+
+// runScan runs both scan scripts locally the way scanRemote runs them over SSH
+func runScan(t *testing.T, dir string, matcher *ignore.Matcher) *remoteTree {
+	t.Helper()
+	scan := newRemoteScan(matcher)
+
+	out, stderr, err := runShell(t, t.TempDir(), remoteListScript(dir))
+	if err != nil {
+		t.Fatalf("list script: %v (stderr: %s)", err, stderr)
+	}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	scanner.Split(scanNUL)
+	for scanner.Scan() {
+		scan.listRecord(scanner.Text())
+	}
+
+	if len(scan.hashPaths) > 0 {
+		cmd := exec.Command("sh", "-c", remoteHashScript(dir, true))
+		cmd.Stdin = strings.NewReader(strings.Join(scan.hashPaths, "\x00") + "\x00")
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("hash script: %v", err)
+		}
+		for _, line := range strings.Split(stdout.String(), "\n") {
+			scan.hashLine(line)
+		}
+	}
+	return scan.tree
+}
+
+func TestScanRemote(t *testing.T) {
+	requireShell(t)
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.txt":             "a",
+		"sub/b.txt":         "b",
+		"logs/skip.log":     "x",
+		"a\nD ./fake":       "n", // 줄바꿈으로 가짜 항목을 만들려는 이름
+		"it's $(touch PWN)": "q",
+	} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Symlink("a.txt", filepath.Join(dir, "link"))
+
+	matcher, err := ignore.New(t.TempDir(), []string{"logs/"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := runScan(t, dir, matcher)
+
+	if !tree.exists {
+		t.Error("directory not reported as existing")
+	}
+	if tree.dirs["fake"] || !tree.dirs["sub"] || tree.dirs["logs"] {
+		t.Errorf("dirs = %v, want only sub", tree.dirs)
+	}
+	if tree.links["link"] != "a.txt" {
+		t.Errorf("links = %v", tree.links)
+	}
+	for _, name := range []string{"a.txt", "sub/b.txt", "a\nD ./fake", "it's $(touch PWN)"} {
+		if !tree.files[name] {
+			t.Errorf("file %q not listed", name)
+		}
+	}
+	if tree.files["logs/skip.log"] || tree.hashes["logs/skip.log"] != "" {
+		t.Error("excluded file was listed or hashed")
+	}
+	// 줄바꿈이 든 이름은 해시하지 않아 항상 다시 업로드됨
+	if _, ok := tree.hashes["a\nD ./fake"]; ok {
+		t.Error("file with a newline was hashed")
+	}
+	if tree.hashes["a.txt"] != fmt.Sprintf("%x", sha256.Sum256([]byte("a"))) ||
+		tree.hashes["it's $(touch PWN)"] != fmt.Sprintf("%x", sha256.Sum256([]byte("q"))) {
+		t.Errorf("hashes = %v", tree.hashes)
+	}
+	if tree.modes["sub/b.txt"].Perm() != 0640 {
+		t.Errorf("mode of sub/b.txt = %v, want 0640", tree.modes["sub/b.txt"])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "PWN")); err == nil {
+		t.Error("file name was run as a command")
+	}
+}
+
+func TestScanRemoteMissingDir(t *testing.T) {
+	requireShell(t)
+	if tree := runScan(t, filepath.Join(t.TempDir(), "missing"), nil); tree.exists || len(tree.files) > 0 {
+		t.Errorf("tree = %+v, want a missing directory", tree)
+	}
+	if tree := runScan(t, t.TempDir(), nil); !tree.exists {
+		t.Error("empty directory not reported as existing")
+	}
+}
@@ -0,0 +1,456 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yejune/gorelay/internal/sftp"
+)
+
+// Transfer backends
+const (
+	TransferAuto = "auto" // SFTP when the server offers it, otherwise scp (default)
+	TransferSFTP = "sftp" // SFTP subsystem only
+	TransferSCP  = "scp"  // Remote scp binary in sink mode (legacy protocol)
+)
+
+// transport copies file contents to and from the remote host.
+// writeFile streams content; size is -1 when it is not known in advance.
+type transport interface {
+	name() string
+	mkdirAll(dir string) error
+	writeFile(remotePath string, content io.Reader, size int64, attrs fileAttrs) error
+	readFile(remotePath string, w io.Writer) (int64, error)
+	symlink(target, link string) (bool, error) // Reports whether the link was (re)created
+	close() error
+}
+
+func validTransfer(mode string) error {
+	switch mode {
+	case "", TransferAuto, TransferSFTP, TransferSCP:
+		return nil
+	}
+	return fmt.Errorf("invalid transfer: %s (expected sftp, scp or auto)", mode)
+}
+
+// transport returns the backend for this connection, opening it on first use.
+// In auto mode the SFTP subsystem is probed once and scp is used if it is missing.
+func (c *Client) transport() (transport, error) {
+	c.transferMu.Lock()
+	defer c.transferMu.Unlock()
+
+	if c.xfer != nil {
+		return c.xfer, nil
+	}
+
+	switch c.transfer {
+	case TransferSCP:
+		c.xfer = &scpTransport{c: c}
+	case TransferSFTP:
+		t, err := c.openSFTP()
+		if err != nil {
+			return nil, err
+		}
+		c.xfer = t
+	default:
+		t, err := c.openSFTP()
+		if err != nil {
+			if c.verbose {
+				fmt.Printf("      SFTP unavailable, falling back to scp: %v\n", err)
+			}
+			c.xfer = &scpTransport{c: c}
+		} else {
+			c.xfer = t
+		}
+	}
+
+	if c.verbose {
+		fmt.Printf("      Transfer: %s\n", c.xfer.name())
+	}
+	return c.xfer, nil
+}
+
+// closeTransport ends a long-lived SFTP session (on Close and Interrupt)
+func (c *Client) closeTransport() {
+	c.transferMu.Lock()
+	defer c.transferMu.Unlock()
+
+	if c.xfer != nil {
+		c.xfer.close()
+		c.xfer = nil
+	}
+}
+
+// sendFile uploads a local file through the transport
+func (c *Client) sendFile(localPath, remotePath string, preserve Preserve) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to read local file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat local file: %w", err)
+	}
+
+	t, err := c.transport()
+	if err != nil {
+		return err
+	}
+
+	// 원격 디렉토리 생성
+	if err := t.mkdirAll(path.Dir(filepath.ToSlash(remotePath))); err != nil {
+		return err
+	}
+	return t.writeFile(remotePath, file, stat.Size(), preserve.attrs(stat))
+}
+
+// sendSymlink recreates a local symlink on the remote host.
+// It reports false when the remote link already pointed to the same target.
+func (c *Client) sendSymlink(localPath, remotePath string) (bool, error) {
+	target, err := os.Readlink(localPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read symlink: %w", err)
+	}
+
+	t, err := c.transport()
+	if err != nil {
+		return false, err
+	}
+
+	if err := t.mkdirAll(path.Dir(filepath.ToSlash(remotePath))); err != nil {
+		return false, err
+	}
+	return t.symlink(filepath.ToSlash(target), remotePath)
+}
+
+// mkdirAll creates a remote directory through the transport
+func (c *Client) mkdirAll(dir string) error {
+	t, err := c.transport()
+	if err != nil {
+		return err
+	}
+	return t.mkdirAll(filepath.ToSlash(dir))
+}
+
+// sftpTransport keeps one SFTP session open for the whole connection
+type sftpTransport struct {
+	c       *Client
+	client  *sftp.Client
+	session interface{ Close() error }
+}
+
+func (c *Client) openSFTP() (*sftpTransport, error) {
+	c.mu.Lock()
+	if c.interrupted {
+		c.mu.Unlock()
+		return nil, ErrInterrupted
+	}
+	c.mu.Unlock()
+
+	// Interrupt가 기다리지 않도록 세션 목록에 넣지 않음 (closeTransport로 닫힘)
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to get stdin pipe: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("sftp subsystem not available: %w", err)
+	}
+
+	client, err := sftp.NewClient(stdout, stdin)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return &sftpTransport{c: c, client: client, session: session}, nil
+}
+
+func (t *sftpTransport) name() string {
+	return TransferSFTP
+}
+
+// sftpPath maps ~/ paths to paths relative to the login directory,
+// since the SFTP server does not expand ~ like the shell does
+func sftpPath(p string) string {
+	if p == "~" {
+		return "."
+	}
+	return strings.TrimPrefix(p, "~/")
+}
+
+func (t *sftpTransport) mkdirAll(dir string) error {
+	if err := t.client.MkdirAll(sftpPath(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create remote directory: %w", err)
+	}
+	return nil
+}
+
+// writeFile uploads to a temporary name in the same directory and renames it
+// over remotePath, so a symlink already at remotePath is replaced, not followed
+func (t *sftpTransport) writeFile(remotePath string, content io.Reader, size int64, attrs fileAttrs) error {
+	tmpPath := path.Join(path.Dir(remotePath), ".gorelay-"+randomID()+".tmp")
+	t.c.trackTemp(tmpPath)
+	defer t.c.untrackTemp(tmpPath)
+
+	err := t.upload(sftpPath(tmpPath), content, size, attrs)
+	if err == nil {
+		err = t.replace(sftpPath(tmpPath), sftpPath(remotePath))
+	}
+	if err != nil {
+		t.client.Remove(sftpPath(tmpPath))
+		return fmt.Errorf("sftp upload failed: %w", err)
+	}
+	return nil
+}
+
+func (t *sftpTransport) upload(remotePath string, content io.Reader, size int64, attrs fileAttrs) error {
+	file, err := t.client.Create(remotePath, attrs.Mode)
+	if err != nil {
+		return err
+	}
+
+	written, err := file.ReadFrom(content)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("wrote %d of %d bytes", written, size)
+	}
+
+	// 생성 시 모드에는 umask가 적용되므로 다시 설정
+	if err := t.client.Chmod(remotePath, attrs.Mode); err != nil {
+		return err
+	}
+	if !attrs.ModTime.IsZero() {
+		if err := t.client.Chtimes(remotePath, attrs.ModTime, attrs.ModTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replace renames tmpPath over remotePath. Without posix-rename the server's
+// RENAME fails when remotePath exists, so a file or symlink there is removed
+// first (not atomic, but only on servers lacking the extension).
+func (t *sftpTransport) replace(tmpPath, remotePath string) error {
+	err := t.client.Rename(tmpPath, remotePath)
+	if err == nil {
+		return nil
+	}
+	attrs, statErr := t.client.Lstat(remotePath)
+	if statErr != nil || attrs.IsDir() {
+		return err
+	}
+	if err := t.client.Remove(remotePath); err != nil {
+		return err
+	}
+	return t.client.Rename(tmpPath, remotePath)
+}
+
+func (t *sftpTransport) readFile(remotePath string, w io.Writer) (int64, error) {
+	file, err := t.client.Open(sftpPath(remotePath))
+	if err != nil {
+		return 0, fmt.Errorf("sftp download failed: %w", err)
+	}
+	defer file.Close()
+
+	n, err := file.WriteTo(w)
+	if err != nil {
+		return n, fmt.Errorf("sftp download failed: %w", err)
+	}
+	return n, nil
+}
+
+func (t *sftpTransport) symlink(target, link string) (bool, error) {
+	link = sftpPath(link)
+	if current, err := t.client.Readlink(link); err == nil && current == target {
+		return false, nil
+	}
+
+	// 기존 파일/링크는 교체 (디렉토리는 지우지 않음)
+	if attrs, err := t.client.Lstat(link); err == nil {
+		if attrs.IsDir() {
+			return false, fmt.Errorf("cannot replace directory %s with a symlink", link)
+		}
+		if err := t.client.Remove(link); err != nil {
+			return false, fmt.Errorf("failed to replace %s: %w", link, err)
+		}
+	}
+
+	if err := t.client.Symlink(target, link); err != nil {
+		return false, fmt.Errorf("failed to create symlink: %w", err)
+	}
+	return true, nil
+}
+
+func (t *sftpTransport) close() error {
+	t.client.Close()
+	return t.session.Close()
+}
+
+// scpTransport runs the remote scp binary in sink mode for each file
+type scpTransport struct {
+	c *Client
+}
+
+func (t *scpTransport) name() string {
+	return TransferSCP
+}
+
+func (t *scpTransport) mkdirAll(dir string) error {
+	if err := t.c.Run(fmt.Sprintf("mkdir -p %s", shellQuote(dir)), io.Discard, io.Discard); err != nil {
+		return fmt.Errorf("failed to create remote directory: %w", err)
+	}
+	return nil
+}
+
+func (t *scpTransport) writeFile(remotePath string, content io.Reader, size int64, attrs fileAttrs) error {
+	// SCP 헤더에는 크기가 필요하므로 크기를 모르는 스트림은 cat으로 받음
+	if size < 0 {
+		return t.writeStream(remotePath, content, attrs.Mode)
+	}
+
+	// 파일 이름은 헤더 한 줄에 들어가므로 줄바꿈은 보낼 수 없음
+	name := path.Base(filepath.ToSlash(remotePath))
+	if strings.ContainsAny(name, "\n\r") {
+		return fmt.Errorf("scp cannot transfer file names with newlines: %q", name)
+	}
+
+	session, err := t.c.newSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer t.c.closeSession(session)
+
+	stdinPipe, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdin pipe: %w", err)
+	}
+
+	var scpStderr bytes.Buffer
+	session.Stderr = &scpStderr
+
+	// SCP 명령 시작 (-t: sink mode, -p: 기존 파일에도 모드/시간 적용)
+	if err := session.Start(fmt.Sprintf("scp -p -t %s", shellQuote(remotePath))); err != nil {
+		return fmt.Errorf("failed to start scp: %w", err)
+	}
+
+	// SCP 프로토콜: 시간 레코드 (T<mtime> 0 <atime> 0), 파일 헤더 (C<mode> <size> <filename>)
+	var header string
+	if !attrs.ModTime.IsZero() {
+		header = fmt.Sprintf("T%d 0 %d 0\n", attrs.ModTime.Unix(), attrs.ModTime.Unix())
+	}
+	header += fmt.Sprintf("C%04o %d %s\n", sftp.PosixMode(attrs.Mode), size, name)
+	if _, err := stdinPipe.Write([]byte(header)); err != nil {
+		return fmt.Errorf("failed to write scp header: %w", err)
+	}
+
+	// 파일 내용 전송
+	if _, err := io.CopyN(stdinPipe, content, size); err != nil {
+		return fmt.Errorf("failed to write file content: %w", err)
+	}
+
+	// 종료 바이트 (0x00)
+	if _, err := stdinPipe.Write([]byte{0}); err != nil {
+		return fmt.Errorf("failed to write terminator: %w", err)
+	}
+
+	stdinPipe.Close()
+
+	if err := session.Wait(); err != nil {
+		return fmt.Errorf("scp failed: %w (stderr: %s)", err, scpStderr.String())
+	}
+
+	return nil
+}
+
+// writeStream pipes content of unknown length into a remote file
+func (t *scpTransport) writeStream(remotePath string, content io.Reader, mode os.FileMode) error {
+	session, err := t.c.newSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer t.c.closeSession(session)
+
+	var stderr bytes.Buffer
+	session.Stdin = content
+	session.Stderr = &stderr
+
+	p := shellQuote(remotePath)
+	cmd := fmt.Sprintf("cat > %s && chmod %04o %s", p, mode.Perm(), p)
+	if err := session.Run(cmd); err != nil {
+		return fmt.Errorf("upload failed: %w (stderr: %s)", err, stderr.String())
+	}
+	return nil
+}
+
+// readFile streams a remote file with cat (the scp source mode adds nothing here)
+func (t *scpTransport) readFile(remotePath string, w io.Writer) (int64, error) {
+	session, err := t.c.newSession()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create session: %w", err)
+	}
+	defer t.c.closeSession(session)
+
+	counter := &countingWriter{}
+	var stderr bytes.Buffer
+	session.Stdout = io.MultiWriter(w, counter)
+	session.Stderr = &stderr
+
+	if err := session.Run(fmt.Sprintf("cat %s", shellQuote(remotePath))); err != nil {
+		return counter.n, fmt.Errorf("download failed: %w (stderr: %s)", err, stderr.String())
+	}
+	return counter.n, nil
+}
+
+// symlink replaces a file or link at link like the SFTP transport does
+func (t *scpTransport) symlink(target, link string) (bool, error) {
+	var stdout, stderr bytes.Buffer
+	if err := t.c.Run(symlinkScript(target, link), &stdout, &stderr); err != nil {
+		return false, fmt.Errorf("failed to create symlink: %w (stderr: %s)", err, stderr.String())
+	}
+	switch strings.TrimSpace(stdout.String()) {
+	case "same":
+		return false, nil
+	case "dir":
+		return false, fmt.Errorf("cannot replace directory %s with a symlink", link)
+	}
+	return true, nil
+}
+
+// symlinkScript prints "same" when link already points to target and "dir"
+// when a directory is in the way; otherwise it replaces link. ln -sfn is not
+// used: it would create the link inside an existing directory.
+func symlinkScript(target, link string) string {
+	return fmt.Sprintf(`l=%s t=%s
+if [ -L "$l" ] && [ "$(readlink -- "$l")" = "$t" ]; then echo same
+elif [ -d "$l" ] && [ ! -L "$l" ]; then echo dir
+else rm -f -- "$l" && ln -s -- "$t" "$l"; fi`, shellQuote(link), quoteWord(target))
+}
+
+func (t *scpTransport) close() error {
+	return nil
+}
@@ -0,0 +1,50 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSymlinkScript(t *testing.T) {
+	requireShell(t)
+
+	tests := []struct {
+		name     string
+		existing func(link string) // What is at the link path before
+		want     string            // Script output
+	}{
+		{"new", func(string) {}, ""},
+		{"same target", func(link string) { os.Symlink("target dir", link) }, "same"},
+		{"other target", func(link string) { os.Symlink("old", link) }, ""},
+		{"link to a directory", func(link string) { os.Symlink(filepath.Join(filepath.Dir(link), "real"), link) }, ""},
+		{"file", func(link string) { os.WriteFile(link, []byte("x"), 0644) }, ""},
+		{"directory", func(link string) { os.Mkdir(link, 0755) }, "dir"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			os.Mkdir(filepath.Join(dir, "real"), 0755)
+			link := filepath.Join(dir, "it's a link")
+			tt.existing(link)
+
+			out, stderr, err := runShell(t, dir, symlinkScript("target dir", link))
+			if err != nil || strings.TrimSpace(out) != tt.want {
+				t.Fatalf("output %q, err %v (stderr: %s); want %q", out, err, stderr, tt.want)
+			}
+			if entries, _ := os.ReadDir(filepath.Join(dir, "real")); len(entries) != 0 {
+				t.Errorf("link created inside the directory it replaced: %v", entries)
+			}
+			if tt.want == "dir" {
+				if info, err := os.Lstat(link); err != nil || !info.IsDir() {
+					t.Errorf("directory was replaced: %v", err)
+				}
+				return
+			}
+			if target, err := os.Readlink(link); err != nil || target != "target dir" {
+				t.Errorf("link -> %q, %v", target, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package ssh
+
+import "os"
+
+// openTerminal opens the controlling terminal for prompts
+func openTerminal() (in, out *os.File, err error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tty, tty, nil
+}
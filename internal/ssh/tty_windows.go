@@ -0,0 +1,20 @@
+//go:build windows
+
+package ssh
+
+import "os"
+
+// openTerminal opens the console for prompts (Windows has no /dev/tty)
+func openTerminal() (in, out *os.File, err error) {
+	// 콘솔 모드를 바꾸려면 입력 핸들에 쓰기 권한도 필요
+	in, err = os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err = os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		in.Close()
+		return nil, nil, err
+	}
+	return in, out, nil
+}